@@ -1,5 +1,11 @@
 package main
 
 import (
+	_ "github.com/koron/funddb/internal/adapter/ammufg"
+	_ "github.com/koron/funddb/internal/adapter/command"
+	_ "github.com/koron/funddb/internal/adapter/fidelity"
+	_ "github.com/koron/funddb/internal/adapter/generic"
+	_ "github.com/koron/funddb/internal/adapter/pictet"
+	_ "github.com/koron/funddb/internal/adapter/tokiomarineam"
 	_ "github.com/koron/funddb/internal/sqlitewrap"
 )
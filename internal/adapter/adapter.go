@@ -0,0 +1,154 @@
+// Package adapter provides a registry of fetch schemes.
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koron/funddb/internal/fundprice"
+)
+
+// FetchFunc retrieves the latest price for an ID in a scheme.
+type FetchFunc func(ctx context.Context, id string) (fundprice.Price, error)
+
+// Scheme describes a fetch scheme provided by an adapter.
+type Scheme struct {
+	Name  string
+	Desc  string
+	Fetch FetchFunc
+
+	// History is optional, nil when the scheme doesn't support history.
+	History fundprice.History
+
+	// DefaultID is optional. It returns an ID for a fund, which is used when
+	// a fetch ID has an empty ID like "{scheme}:". Empty IDs are invalid
+	// when nil.
+	DefaultID func(fundID string) string
+
+	// CheckID is optional. It checks an ID in fetch IDs, like its syntax or
+	// names declared in the config file.
+	CheckID func(id string) error
+}
+
+var (
+	mu      sync.RWMutex
+	schemes = map[string]Scheme{}
+)
+
+// Register registers a scheme. It panics when the name is empty, fetch
+// function is nil or the name is registered already.
+func Register(s Scheme) {
+	mu.Lock()
+	defer mu.Unlock()
+	if s.Name == "" {
+		panic("adapter: empty scheme name")
+	}
+	if s.Fetch == nil {
+		panic("adapter: nil fetch function for " + s.Name)
+	}
+	if _, ok := schemes[s.Name]; ok {
+		panic("adapter: scheme registered twice: " + s.Name)
+	}
+	schemes[s.Name] = s
+}
+
+// Lookup finds a registered scheme by name.
+func Lookup(name string) (Scheme, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := schemes[name]
+	return s, ok
+}
+
+// Schemes returns all registered schemes sorted by name.
+func Schemes() []Scheme {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]Scheme, 0, len(schemes))
+	for _, s := range schemes {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// ParseFetchID splits a fetch ID "{scheme}:{id}" into its parts.
+func ParseFetchID(fetchID string) (scheme, id string, err error) {
+	scheme, id, ok := strings.Cut(fetchID, ":")
+	if !ok {
+		return "", "", fmt.Errorf("invalid fetch ID, required format \"{scheme}:{id}\": %s", fetchID)
+	}
+	return scheme, id, nil
+}
+
+// ExpandFetchID fills an empty ID of a fetch ID with the default ID of its
+// scheme for a fund. Other fetch IDs are returned as is.
+func ExpandFetchID(fetchID, fundID string) string {
+	scheme, id, err := ParseFetchID(fetchID)
+	if err != nil || id != "" {
+		return fetchID
+	}
+	s, ok := Lookup(scheme)
+	if !ok || s.DefaultID == nil {
+		return fetchID
+	}
+	return scheme + ":" + s.DefaultID(fundID)
+}
+
+// Validate checks a fetch ID has a registered scheme, and a valid ID for
+// the scheme.
+func Validate(fetchID string) error {
+	scheme, id, err := ParseFetchID(fetchID)
+	if err != nil {
+		return err
+	}
+	s, ok := Lookup(scheme)
+	if !ok {
+		return fmt.Errorf("unknown scheme: %s", scheme)
+	}
+	if id == "" {
+		return fmt.Errorf("empty ID in fetch ID: %s", fetchID)
+	}
+	if s.CheckID != nil {
+		if err := s.CheckID(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fetch retrieves the latest price for a fetch ID with a registered scheme.
+func Fetch(ctx context.Context, fetchID string) (fundprice.Price, error) {
+	scheme, id, err := ParseFetchID(fetchID)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := Lookup(scheme)
+	if !ok {
+		return nil, fmt.Errorf("unknown scheme: %s", scheme)
+	}
+	return s.Fetch(ctx, id)
+}
+
+// FetchHistory retrieves prices between from and to for a fetch ID with a
+// registered scheme which supports history.
+func FetchHistory(ctx context.Context, fetchID string, from, to time.Time) ([]fundprice.Price, error) {
+	scheme, id, err := ParseFetchID(fetchID)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := Lookup(scheme)
+	if !ok {
+		return nil, fmt.Errorf("unknown scheme: %s", scheme)
+	}
+	if s.History == nil {
+		return nil, fmt.Errorf("scheme %s doesn't support history", scheme)
+	}
+	return s.History.History(ctx, id, from, to)
+}
@@ -0,0 +1,81 @@
+package adapter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/fundprice"
+)
+
+type testPrice struct {
+	id string
+}
+
+func (p testPrice) Date() time.Time  { return time.Time{} }
+func (p testPrice) Price() int64     { return 1 }
+func (p testPrice) NetAssets() int64 { return 2 }
+
+func TestFetch(t *testing.T) {
+	adapter.Register(adapter.Scheme{
+		Name: "test",
+		Desc: "test scheme",
+		Fetch: func(ctx context.Context, id string) (fundprice.Price, error) {
+			return testPrice{id: id}, nil
+		},
+	})
+	p, err := adapter.Fetch(context.Background(), "test:foo:bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.(testPrice).id, "foo:bar"; got != want {
+		t.Errorf("unmatch ID: want=%s got=%s", want, got)
+	}
+
+	for _, fetchID := range []string{"test", "unknown:foo"} {
+		_, err := adapter.Fetch(context.Background(), fetchID)
+		if err == nil {
+			t.Errorf("no errors for %q", fetchID)
+		}
+	}
+
+	var found bool
+	for _, s := range adapter.Schemes() {
+		if s.Name == "test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("scheme \"test\" is not listed")
+	}
+}
+
+func TestExpandFetchID(t *testing.T) {
+	adapter.Register(adapter.Scheme{
+		Name: "testdefault",
+		Desc: "test scheme with default IDs",
+		Fetch: func(ctx context.Context, id string) (fundprice.Price, error) {
+			return testPrice{id: id}, nil
+		},
+		DefaultID: func(fundID string) string {
+			return "code=" + fundID
+		},
+	})
+	for _, c := range []struct {
+		fetchID string
+		want    string
+	}{
+		{"testdefault:", "testdefault:code=0331418A"},
+		{"testdefault:other", "testdefault:other"},
+		{"unknown:", "unknown:"},
+		{"invalid", "invalid"},
+	} {
+		if got := adapter.ExpandFetchID(c.fetchID, "0331418A"); got != c.want {
+			t.Errorf("unmatch for %q: want=%s got=%s", c.fetchID, c.want, got)
+		}
+	}
+	if err := adapter.Validate("testdefault:"); err == nil {
+		t.Error("empty ID should be invalid before expansion")
+	}
+}
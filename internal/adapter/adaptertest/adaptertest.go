@@ -0,0 +1,42 @@
+// Package adaptertest provides utilities for tests of adapters.
+package adaptertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koron/funddb/internal/adapter"
+)
+
+// Route is a response for a request URI: a status and a file in testdata.
+type Route struct {
+	Status int
+	File   string
+}
+
+// NewServer starts a server which responds files in testdata for request
+// URIs with a content type, and returns options to access it.
+func NewServer(t testing.TB, contentType string, routes map[string]Route) adapter.Options {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := routes[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		b, err := os.ReadFile(filepath.Join("testdata", rt.File))
+		if err != nil {
+			t.Errorf("failed to read fixture: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(rt.Status)
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return adapter.Options{HTTPClient: srv.Client(), BaseURL: srv.URL}
+}
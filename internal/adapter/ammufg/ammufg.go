@@ -8,11 +8,22 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/decimal"
+	"github.com/koron/funddb/internal/fundprice"
 )
 
+// Number is a number in a string. It is empty when not available.
 type Number string
 
+// Decimal parses n into a decimal.
+func (n Number) Decimal() (decimal.Decimal, error) {
+	return decimal.Parse(string(n))
+}
+
 type Dataset struct {
 	FundCD            string `json:"fund_cd"`
 	AssociationFundCD string `json:"association_fund_cd"`
@@ -109,6 +120,88 @@ func (ds Dataset) NetAssets() int64 {
 	return ds.NetAssets_
 }
 
+// AssociationCode returns the association fund code, to cross-check with
+// IDs of funds.
+func (ds Dataset) AssociationCode() string {
+	return ds.AssociationFundCD
+}
+
+// Metrics returns figures of the dataset keyed by names in JSON: risks,
+// returns, percentage changes and highest/lowest prices with their dates.
+// Unavailable or invalid figures are omitted.
+func (ds Dataset) Metrics() map[string]fundprice.Metric {
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	m := map[string]fundprice.Metric{}
+	add := func(name string, n Number, date string) {
+		if n == "" {
+			return
+		}
+		v, err := n.Decimal()
+		if err != nil {
+			log.Printf("invalid %s of %s: %s", name, ds.FundCD, err)
+			return
+		}
+		metric := fundprice.Metric{Value: v}
+		if date != "" {
+			metric.RefDate, err = time.ParseInLocation("20060102", date, loc)
+			if err != nil {
+				log.Printf("invalid %s_dt of %s: %s", name, ds.FundCD, err)
+			}
+		}
+		m[name] = metric
+	}
+	add("netassets_change_cmp_prev_day", Number(ds.NetAssetsChangeCmpPrevDay), "")
+	for _, x := range []struct {
+		name string
+		n    Number
+		date string
+	}{
+		{"nav_max_1m", ds.NavMax1m, ds.NavMax1mDt},
+		{"nav_max_3m", ds.NavMax3m, ds.NavMax3mDt},
+		{"nav_max_6m", ds.NavMax6m, ds.NavMax6mDt},
+		{"nav_max_1y", ds.NavMax1y, ds.NavMax1yDt},
+		{"nav_max_full", ds.NavMaxFull, ds.NavMaxFullDt},
+		{"nav_min_1m", ds.NavMin1m, ds.NavMin1mDt},
+		{"nav_min_3m", ds.NavMin3m, ds.NavMin3mDt},
+		{"nav_min_6m", ds.NavMin6m, ds.NavMin6mDt},
+		{"nav_min_1y", ds.NavMin1y, ds.NavMin1yDt},
+		{"nav_min_full", ds.NavMinFull, ds.NavMinFullDt},
+	} {
+		add(x.name, x.n, x.date)
+	}
+	for name, n := range map[string]Number{
+		"percentage_change":          ds.PercentageChange,
+		"percentage_change_1m":       ds.PercentageChange1m,
+		"percentage_change_3m":       ds.PercentageChange3m,
+		"percentage_change_6m":       ds.PercentageChange6m,
+		"percentage_change_1y":       ds.PercentageChange1y,
+		"percentage_change_full":     ds.PercentageChangeFull,
+		"percentage_change_max_1m":   ds.PercentageChangeMax1m,
+		"percentage_change_max_3m":   ds.PercentageChangeMax3m,
+		"percentage_change_max_6m":   ds.PercentageChangeMax6m,
+		"percentage_change_max_1y":   ds.PercentageChangeMax1y,
+		"percentage_change_max_full": ds.PercentageChangeMaxFull,
+		"percentage_change_min_1m":   ds.PercentageChangeMin1m,
+		"percentage_change_min_3m":   ds.PercentageChangeMin3m,
+		"percentage_change_min_6m":   ds.PercentageChangeMin6m,
+		"percentage_change_min_1y":   ds.PercentageChangeMin1y,
+		"percentage_change_min_full": ds.PercentageChangeMinFull,
+		"risk_1y":                    ds.Risk1y,
+		"risk_3y":                    ds.Risk3y,
+		"risk_full":                  ds.RiskFull,
+		"risk_return_1y":             ds.RiskReturn1y,
+		"risk_return_3y":             ds.RiskReturn3y,
+		"risk_return_full":           ds.RiskReturnFull,
+	} {
+		add(name, n, "")
+	}
+	return m
+}
+
 type Error struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
@@ -147,7 +240,11 @@ func (er errorResult) Error() string {
 		return fmt.Sprintf("%s (code: %s)", er.Result.ErrMsg, er.Result.ErrCD)
 	}
 	if len(er.Errors.ErrorList) > 0 {
-		return er.Error()
+		errs := make([]error, len(er.Errors.ErrorList))
+		for i, e := range er.Errors.ErrorList {
+			errs[i] = e
+		}
+		return errors.Join(errs...).Error()
 	}
 	return fmt.Sprintf("failed something status:%d retcount:%d errors.count:%d", er.Result.Status, er.Result.RetCount, er.Errors.Count)
 }
@@ -160,14 +257,77 @@ const (
 	CodeTypeFund            CodeType = "fund_cd"
 )
 
-// Get retrives latest fund information (Dataset) by code and its type.
+// idKeys maps keys of IDs in fetch IDs to code types.
+var idKeys = map[string]CodeType{
+	"fund":  CodeTypeFund,
+	"isin":  CodeTypeISIN,
+	"assoc": CodeTypeAssociationFund,
+}
+
+// ParseID parses an ID in fetch IDs: "isin={ISIN}", "assoc={association
+// fund code}", "fund={fund code}" or a plain fund code.
+func ParseID(id string) (CodeType, string, error) {
+	key, code, ok := strings.Cut(id, "=")
+	if !ok {
+		return CodeTypeFund, id, nil
+	}
+	ct, ok := idKeys[key]
+	if !ok {
+		return "", "", fmt.Errorf("unknown key %q in ID %q, available keys are: fund, isin, assoc", key, id)
+	}
+	if code == "" {
+		return "", "", fmt.Errorf("empty code in ID %q", id)
+	}
+	return ct, code, nil
+}
+
+// DefaultBaseURL is the base URL of the MUFG developer API.
+const DefaultBaseURL = "https://developer.am.mufg.jp"
+
+// Client is a client of the MUFG developer API.
+type Client struct {
+	opts adapter.Options
+}
+
+// NewClient creates a new Client with options.
+func NewClient(opts adapter.Options) *Client {
+	return &Client{opts: opts}
+}
+
+// Get retrives latest fund information (Dataset) by code and its type, with
+// options for "ammufg" scheme.
 func Get(ctx context.Context, ct CodeType, code string) (*Dataset, error) {
-	u := fmt.Sprintf("https://developer.am.mufg.jp/fund_information_latest/%s/%s", ct, code)
-	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	return NewClient(adapter.OptionsFor("ammufg")).Get(ctx, ct, code)
+}
+
+// Get retrives latest fund information (Dataset) by code and its type.
+func (c *Client) Get(ctx context.Context, ct CodeType, code string) (*Dataset, error) {
+	u := c.opts.URL(DefaultBaseURL, fmt.Sprintf("/fund_information_latest/%s/%s", ct, code))
+	datasets, err := c.get(ctx, u)
 	if err != nil {
 		return nil, err
 	}
-	res, err := http.DefaultClient.Do(req)
+	if len(datasets) < 1 {
+		return nil, errors.New("no datasets available in API response")
+	}
+	return &datasets[0], nil
+}
+
+// GetHistory retrieves fund information (Datasets) from "from" to "to"
+// inclusive by code and its type, with options for "ammufg" scheme.
+func GetHistory(ctx context.Context, ct CodeType, code string, from, to time.Time) ([]Dataset, error) {
+	return NewClient(adapter.OptionsFor("ammufg")).GetHistory(ctx, ct, code, from, to)
+}
+
+// GetHistory retrieves fund information (Datasets) from "from" to "to"
+// inclusive by code and its type.
+func (c *Client) GetHistory(ctx context.Context, ct CodeType, code string, from, to time.Time) ([]Dataset, error) {
+	u := c.opts.URL(DefaultBaseURL, fmt.Sprintf("/fund_information_history/%s/%s?start_date=%s&end_date=%s", ct, code, from.Format("20060102"), to.Format("20060102")))
+	return c.get(ctx, u)
+}
+
+func (c *Client) get(ctx context.Context, u string) ([]Dataset, error) {
+	res, err := c.opts.Get(ctx, u)
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +339,7 @@ func Get(ctx context.Context, ct CodeType, code string) (*Dataset, error) {
 		} else {
 			log.Printf("[INFO] error:%d reponse:\n%s", res.StatusCode, string(all))
 		}
-		return nil, fmt.Errorf("failed HTTP with %d for: %q", res.StatusCode, u)
+		return nil, adapter.NewStatusError(res, fmt.Errorf("failed HTTP with %d for: %q", res.StatusCode, u))
 	}
 	var data FundInfo
 	err = json.NewDecoder(res.Body).Decode(&data)
@@ -190,8 +350,49 @@ func Get(ctx context.Context, ct CodeType, code string) (*Dataset, error) {
 	if data.Result.Status != 200 || data.Errors.Count != 0 {
 		return nil, &errorResult{Result: data.Result, Errors: data.Errors}
 	}
-	if len(data.Datasets) < 1 {
-		return nil, errors.New("no datasets available in API response")
+	return data.Datasets, nil
+}
+
+type history struct{}
+
+func (history) History(ctx context.Context, id string, from, to time.Time) ([]fundprice.Price, error) {
+	ct, code, err := ParseID(id)
+	if err != nil {
+		return nil, err
 	}
-	return &data.Datasets[0], nil
+	datasets, err := GetHistory(ctx, ct, code, from, to)
+	if err != nil {
+		return nil, err
+	}
+	prices := make([]fundprice.Price, len(datasets))
+	for i, d := range datasets {
+		prices[i] = d
+	}
+	return prices, nil
+}
+
+func init() {
+	adapter.Register(adapter.Scheme{
+		Name: "ammufg",
+		Desc: "Mitsubishi UFJ Asset Management (fund code, isin=ISIN, assoc=association code, or empty for the fund ID)",
+		Fetch: func(ctx context.Context, id string) (fundprice.Price, error) {
+			ct, code, err := ParseID(id)
+			if err != nil {
+				return nil, err
+			}
+			d, err := Get(ctx, ct, code)
+			if err != nil {
+				return nil, err
+			}
+			return d, nil
+		},
+		History: history{},
+		DefaultID: func(fundID string) string {
+			return "assoc=" + fundID
+		},
+		CheckID: func(id string) error {
+			_, _, err := ParseID(id)
+			return err
+		},
+	})
 }
@@ -2,12 +2,203 @@ package ammufg_test
 
 import (
 	"context"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/adapter/adaptertest"
 	"github.com/koron/funddb/internal/adapter/ammufg"
+	"github.com/koron/funddb/internal/fundprice"
 )
 
+func TestClientGet(t *testing.T) {
+	c := ammufg.NewClient(adaptertest.NewServer(t, "application/json", map[string]adaptertest.Route{
+		"/fund_information_latest/fund_cd/253425":               {Status: 200, File: "253425.json"},
+		"/fund_information_latest/association_fund_cd/0331418A": {Status: 200, File: "253425.json"},
+		"/fund_information_latest/isin_cd/JP90C000H1T1":         {Status: 200, File: "253425.json"},
+		"/fund_information_latest/fund_cd/000000":               {Status: 200, File: "errmsg.json"},
+		"/fund_information_latest/fund_cd/invalid":              {Status: 200, File: "errorlist.json"},
+		"/fund_information_latest/fund_cd/empty":                {Status: 200, File: "nodatasets.json"},
+		"/fund_information_latest/fund_cd/unavailable":          {Status: 503, File: "errmsg.json"},
+		"/fund_information_latest/association_fund_cd/broken":   {Status: 200, File: "broken.json"},
+	}))
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c0 := range []struct {
+		ctype ammufg.CodeType
+		code  string
+	}{
+		{ammufg.CodeTypeFund, "253425"},
+		{ammufg.CodeTypeAssociationFund, "0331418A"},
+		{ammufg.CodeTypeISIN, "JP90C000H1T1"},
+	} {
+		loc0 := string(c0.ctype) + ":" + c0.code
+		d, err := c.Get(context.Background(), c0.ctype, c0.code)
+		if err != nil {
+			t.Errorf("failed to get %q: %v", loc0, err)
+			continue
+		}
+		if got, want := d.Date(), time.Date(2024, 6, 24, 18, 0, 0, 0, loc); !got.Equal(want) {
+			t.Errorf("unmatched date for %q: want=%s got=%s", loc0, want, got)
+		}
+		if got, want := d.ID(), "253425"; got != want {
+			t.Errorf("unmatched ID for %q: want=%s got=%s", loc0, want, got)
+		}
+		if got, want := d.Price(), int64(24367); got != want {
+			t.Errorf("unmatched price for %q: want=%d got=%d", loc0, want, got)
+		}
+		if got, want := d.NetAssets(), int64(3462714000000); got != want {
+			t.Errorf("unmatched net assets for %q: want=%d got=%d", loc0, want, got)
+		}
+	}
+
+	for _, c0 := range []struct {
+		ctype   ammufg.CodeType
+		code    string
+		wantErr string
+	}{
+		{ammufg.CodeTypeFund, "000000", "fund not found (code: E0001)"},
+		{ammufg.CodeTypeFund, "invalid", "invalid code type (code: V0001)\ninvalid code (code: V0002)"},
+		{ammufg.CodeTypeFund, "empty", "no datasets available in API response"},
+		{ammufg.CodeTypeFund, "unavailable", "failed HTTP with 503"},
+		{ammufg.CodeTypeFund, "unknown", "failed HTTP with 404"},
+		{ammufg.CodeTypeAssociationFund, "broken", "failed to parse JSON"},
+	} {
+		loc0 := string(c0.ctype) + ":" + c0.code
+		_, err := c.Get(context.Background(), c0.ctype, c0.code)
+		if err == nil {
+			t.Errorf("no errors for %q", loc0)
+			continue
+		}
+		if !strings.Contains(err.Error(), c0.wantErr) {
+			t.Errorf("unexpected error for %q: want=%q got=%q", loc0, c0.wantErr, err)
+		}
+	}
+}
+
+func TestClientGetHistory(t *testing.T) {
+	c := ammufg.NewClient(adaptertest.NewServer(t, "application/json", map[string]adaptertest.Route{
+		"/fund_information_history/fund_cd/253425?start_date=20240620&end_date=20240624": {Status: 200, File: "history.json"},
+	}))
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 6, 20, 0, 0, 0, 0, loc)
+	to := time.Date(2024, 6, 24, 0, 0, 0, 0, loc)
+	list, err := c.GetHistory(context.Background(), ammufg.CodeTypeFund, "253425", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		day   int
+		price int64
+	}{
+		{20, 24400},
+		{21, 24280},
+		{24, 24367},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("unmatch length: want=%d got=%d", len(want), len(list))
+	}
+	for i, w := range want {
+		if got, want := list[i].Date(), time.Date(2024, 6, w.day, 18, 0, 0, 0, loc); !got.Equal(want) {
+			t.Errorf("unmatch date #%d: want=%s got=%s", i, want, got)
+		}
+		if got := list[i].Price(); got != w.price {
+			t.Errorf("unmatch price #%d: want=%d got=%d", i, w.price, got)
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	for _, c := range []struct {
+		id       string
+		wantType ammufg.CodeType
+		wantCode string
+	}{
+		{"253425", ammufg.CodeTypeFund, "253425"},
+		{"fund=253425", ammufg.CodeTypeFund, "253425"},
+		{"isin=JP90C000H1T1", ammufg.CodeTypeISIN, "JP90C000H1T1"},
+		{"assoc=0331418A", ammufg.CodeTypeAssociationFund, "0331418A"},
+	} {
+		ct, code, err := ammufg.ParseID(c.id)
+		if err != nil {
+			t.Errorf("failed to parse %q: %s", c.id, err)
+			continue
+		}
+		if ct != c.wantType || code != c.wantCode {
+			t.Errorf("unmatch for %q: want=(%s, %s) got=(%s, %s)", c.id, c.wantType, c.wantCode, ct, code)
+		}
+	}
+	for _, id := range []string{"isn=JP90C000H1T1", "assoc="} {
+		if _, _, err := ammufg.ParseID(id); err == nil {
+			t.Errorf("no errors for %q", id)
+		}
+	}
+	if got, want := adapter.ExpandFetchID("ammufg:", "0331418A"), "ammufg:assoc=0331418A"; got != want {
+		t.Errorf("unmatch default ID: want=%s got=%s", want, got)
+	}
+	if err := adapter.Validate("ammufg:isn=JP90C000H1T1"); err == nil {
+		t.Errorf("unknown key should be invalid")
+	}
+}
+
+func TestDatasetMetrics(t *testing.T) {
+	c := ammufg.NewClient(adaptertest.NewServer(t, "application/json", map[string]adaptertest.Route{
+		"/fund_information_latest/fund_cd/253425": {Status: 200, File: "253425.json"},
+	}))
+	d, err := c.Get(context.Background(), ammufg.CodeTypeFund, "253425")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := d.Metrics()
+	if n := len(m); n != 33 {
+		t.Errorf("unexpected number of metrics: %d", n)
+	}
+	for name, want := range map[string]fundprice.Metric{
+		"risk_1y":                       {Value: "11.52"},
+		"risk_return_full":              {Value: "1.12"},
+		"percentage_change_min_full":    {Value: "-8.34"},
+		"netassets_change_cmp_prev_day": {Value: "1234"},
+		"nav_min_full":                  {Value: "9332", RefDate: time.Date(2020, 3, 23, 0, 0, 0, 0, loc)},
+	} {
+		got, ok := m[name]
+		if !ok {
+			t.Errorf("not found metric %s", name)
+			continue
+		}
+		if got.Value != want.Value || !got.RefDate.Equal(want.RefDate) {
+			t.Errorf("unmatch metric %s: want=%+v got=%+v", name, want, got)
+		}
+	}
+
+	d.Risk3y = "-"
+	d.RiskFull = ""
+	m = d.Metrics()
+	if _, ok := m["risk_3y"]; ok {
+		t.Errorf("invalid metric should be omitted")
+	}
+	if _, ok := m["risk_full"]; ok {
+		t.Errorf("empty metric should be omitted")
+	}
+}
+
+// TestGet accesses the production server. It runs only when FUNDDB_LIVE_TEST
+// is set.
 func TestGet(t *testing.T) {
+	if os.Getenv("FUNDDB_LIVE_TEST") == "" {
+		t.Skip("FUNDDB_LIVE_TEST is not set")
+	}
 	ctx := context.Background()
 
 	for _, c := range []struct {
@@ -0,0 +1,276 @@
+// Package command provides the "exec" scheme, which runs commands declared
+// in the config file to fetch prices. A fetch ID "exec:{command}:{code}"
+// names a command and a code of a fund, which is given to the command as
+// the last argument.
+//
+// A command writes a JSON document to stdout and exits with 0:
+//
+//	{"date": "2024-06-24", "price": 12345, "net_assets": 45678000000,
+//	 "distributions": [{"ex_date": "2024-06-10", "amount": 100}]}
+//
+// net_assets and distributions are optional. Exit status 75 (EX_TEMPFAIL)
+// reports a temporary failure, which is retried.
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/fundprice"
+)
+
+// DefaultTimeout limits the time for a command, when it is not specified.
+const DefaultTimeout = 30 * time.Second
+
+// ExitTempFail is an exit status for temporary failures.
+const ExitTempFail = 75
+
+// maxStderr is the maximum length of stderr in errors.
+const maxStderr = 4096
+
+// Command is a declaration of a command.
+type Command struct {
+	// Path is a path of an executable.
+	Path string
+
+	// Args are arguments given before a code of a fund.
+	Args []string
+
+	// Env are additional environment variables, "KEY=VALUE".
+	Env []string
+
+	// Timeout limits the time for a run when positive, DefaultTimeout is
+	// used otherwise.
+	Timeout time.Duration
+}
+
+var (
+	mu       sync.RWMutex
+	commands = map[string]Command{}
+)
+
+// SetCommands replaces all declared commands.
+func SetCommands(m map[string]Command) {
+	mu.Lock()
+	commands = m
+	mu.Unlock()
+}
+
+// LookupCommand finds a declared command by name.
+func LookupCommand(name string) (Command, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := commands[name]
+	return c, ok
+}
+
+// ExitError is an error for a command which exits with non-zero status.
+type ExitError struct {
+	Name   string
+	Code   int
+	Stderr string
+}
+
+func (err *ExitError) Error() string {
+	msg := fmt.Sprintf("command %s exited with status %d", err.Name, err.Code)
+	if err.Stderr != "" {
+		msg += ": " + err.Stderr
+	}
+	return msg
+}
+
+// Temporary reports whether the command failed temporarily.
+func (err *ExitError) Temporary() bool {
+	return err.Code == ExitTempFail
+}
+
+type Data struct {
+	id            string
+	date          time.Time
+	price         int64
+	netAssets     int64
+	distributions []fundprice.Distribution
+}
+
+func (d Data) Scheme() string {
+	return "exec"
+}
+
+func (d Data) ID() string {
+	return d.id
+}
+
+func (d Data) Date() time.Time {
+	return d.date
+}
+
+func (d Data) Price() int64 {
+	return d.price
+}
+
+func (d Data) NetAssets() int64 {
+	return d.netAssets
+}
+
+func (d Data) Distributions() []fundprice.Distribution {
+	return d.distributions
+}
+
+// document is an output of commands.
+type document struct {
+	Date          dataobj.Date `json:"date"`
+	Price         int64        `json:"price"`
+	NetAssets     int64        `json:"net_assets"`
+	Distributions []struct {
+		ExDate dataobj.Date `json:"ex_date"`
+		Amount int64        `json:"amount"`
+	} `json:"distributions"`
+}
+
+func parse(b []byte) (*Data, error) {
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("invalid output: %w", err)
+	}
+	var errs []error
+	if doc.Date.IsZero() {
+		errs = append(errs, errors.New("not found date"))
+	}
+	if doc.Price <= 0 {
+		errs = append(errs, errors.New("not found price"))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		return nil, err
+	}
+	d := &Data{
+		date:      doc.Date.Time(loc).Add(time.Hour * 18),
+		price:     doc.Price,
+		netAssets: doc.NetAssets,
+	}
+	for _, dist := range doc.Distributions {
+		d.distributions = append(d.distributions, fundprice.Distribution{
+			ExDate: dist.ExDate.Time(loc),
+			Amount: dist.Amount,
+		})
+	}
+	return d, nil
+}
+
+// trimStderr shortens stderr for errors, keeping its tail.
+func trimStderr(b []byte) string {
+	b = bytes.TrimSpace(b)
+	if len(b) > maxStderr {
+		b = append([]byte("..."), b[len(b)-maxStderr:]...)
+	}
+	return strings.ToValidUTF8(string(b), "?")
+}
+
+// Run runs a command for a code of a fund, and parses its output. name is
+// used in errors.
+func (c Command) Run(ctx context.Context, name, code string) (*Data, error) {
+	if c.Path == "" {
+		return nil, fmt.Errorf("command %s: no path", name)
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Path, append(append([]string{}, c.Args...), code)...)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// stop waiting for outputs of children which the command left.
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if !errors.Is(ctxErr, context.DeadlineExceeded) {
+			return nil, ctxErr
+		}
+		err := fmt.Errorf("command %s timed out after %s: %w", name, timeout, ctxErr)
+		if s := trimStderr(stderr.Bytes()); s != "" {
+			err = fmt.Errorf("%w: %s", err, s)
+		}
+		return nil, err
+	}
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return nil, &ExitError{Name: name, Code: ee.ExitCode(), Stderr: trimStderr(stderr.Bytes())}
+		}
+		return nil, fmt.Errorf("command %s: %w", name, err)
+	}
+	d, err := parse(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("command %s: %w", name, err)
+	}
+	d.id = code
+	return d, nil
+}
+
+// parseID parses an ID in fetch IDs without the scheme: "{command}:{code}",
+// and finds the declared command.
+func parseID(id string) (string, Command, string, error) {
+	name, code, ok := strings.Cut(id, ":")
+	if !ok || name == "" || code == "" {
+		return "", Command{}, "", fmt.Errorf("invalid ID %q, which should be {command}:{code}", id)
+	}
+	c, ok := LookupCommand(name)
+	if !ok {
+		return "", Command{}, "", fmt.Errorf("unknown command %q, which should be declared in \"commands\" of the config file", name)
+	}
+	return name, c, code, nil
+}
+
+// Get runs a declared command for a fetch ID without the scheme:
+// "{command}:{code}".
+func Get(ctx context.Context, id string) (*Data, error) {
+	name, c, code, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	d, err := c.Run(ctx, name, code)
+	if err != nil {
+		return nil, err
+	}
+	d.id = id
+	return d, nil
+}
+
+func init() {
+	adapter.Register(adapter.Scheme{
+		Name: "exec",
+		Desc: "Commands declared in the config file ({command}:{code})",
+		Fetch: func(ctx context.Context, id string) (fundprice.Price, error) {
+			d, err := Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return d, nil
+		},
+		CheckID: func(id string) error {
+			_, _, _, err := parseID(id)
+			return err
+		},
+	})
+}
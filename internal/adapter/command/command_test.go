@@ -0,0 +1,154 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/adapter/command"
+	"github.com/koron/funddb/internal/fundprice"
+)
+
+// TestMain runs the test binary as a fetch command, when FUNDDB_TEST_COMMAND
+// is set. The last argument selects a behavior.
+func TestMain(m *testing.M) {
+	if os.Getenv("FUNDDB_TEST_COMMAND") == "" {
+		os.Exit(m.Run())
+	}
+	switch os.Args[len(os.Args)-1] {
+	case "ok":
+		fmt.Println(`{"date": "2024-06-24", "price": 12345, "net_assets": 45678000000, "distributions": [{"ex_date": "2024-06-10", "amount": 100}]}`)
+	case "noprice":
+		fmt.Println(`{"date": "2024-06-24"}`)
+	case "broken":
+		fmt.Println(`<html>`)
+	case "fail":
+		fmt.Fprintln(os.Stderr, "login required")
+		os.Exit(3)
+	case "tempfail":
+		fmt.Fprintln(os.Stderr, "service unavailable")
+		os.Exit(command.ExitTempFail)
+	case "slow":
+		fmt.Fprintln(os.Stderr, "waiting")
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func testCommand(t *testing.T) command.Command {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return command.Command{Path: exe, Env: []string{"FUNDDB_TEST_COMMAND=1"}, Timeout: 10 * time.Second}
+}
+
+func TestRun(t *testing.T) {
+	c := testCommand(t)
+	d, err := c.Run(context.Background(), "test", "ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.Date(), time.Date(2024, 6, 24, 18, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("unmatched date: want=%s got=%s", want, got)
+	}
+	if d.Price() != 12345 || d.NetAssets() != 45_678_000_000 || d.ID() != "ok" {
+		t.Errorf("unexpected data: %+v", d)
+	}
+	want := []fundprice.Distribution{{ExDate: time.Date(2024, 6, 10, 0, 0, 0, 0, loc), Amount: 100}}
+	got := d.Distributions()
+	if len(got) != 1 || !got[0].ExDate.Equal(want[0].ExDate) || got[0].Amount != want[0].Amount {
+		t.Errorf("unmatched distributions: want=%v got=%v", want, got)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	c := testCommand(t)
+	for _, tc := range []struct {
+		code      string
+		wantErr   string
+		retryable bool
+	}{
+		{"noprice", "command test: not found price", false},
+		{"broken", "command test: invalid output", false},
+		{"fail", "command test exited with status 3: login required", false},
+		{"tempfail", "command test exited with status 75: service unavailable", true},
+	} {
+		_, err := c.Run(context.Background(), "test", tc.code)
+		if err == nil {
+			t.Errorf("no errors for %q", tc.code)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("unexpected error for %q: want=%q got=%q", tc.code, tc.wantErr, err)
+		}
+		if ok, _ := adapter.Retryable(err); ok != tc.retryable {
+			t.Errorf("unexpected retryable for %q: want=%t got=%t", tc.code, tc.retryable, ok)
+		}
+	}
+
+	var ee *command.ExitError
+	if _, err := c.Run(context.Background(), "test", "fail"); !errors.As(err, &ee) || ee.Code != 3 {
+		t.Errorf("should be ExitError: %#v", err)
+	}
+
+	c.Timeout = 200 * time.Millisecond
+	start := time.Now()
+	_, err := c.Run(context.Background(), "test", "slow")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") || !strings.Contains(err.Error(), "waiting") {
+		t.Errorf("unexpected error for timeout: %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout should be retryable: %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("too long to time out: %s", d)
+	}
+}
+
+func TestGet(t *testing.T) {
+	command.SetCommands(map[string]command.Command{"test": testCommand(t)})
+	t.Cleanup(func() { command.SetCommands(nil) })
+	p, err := adapter.Fetch(context.Background(), "exec:test:ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Price() != 12345 {
+		t.Errorf("unexpected price: %d", p.Price())
+	}
+	if _, err := adapter.Fetch(context.Background(), "exec:unknown:ok"); err == nil || !strings.Contains(err.Error(), `unknown command "unknown"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckID(t *testing.T) {
+	command.SetCommands(map[string]command.Command{"test": testCommand(t)})
+	t.Cleanup(func() { command.SetCommands(nil) })
+	if err := adapter.Validate("exec:test:ok"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for _, tc := range []struct {
+		fetchID string
+		wantErr string
+	}{
+		{"exec:tset:ok", `unknown command "tset"`},
+		{"exec:test", "should be {command}:{code}"},
+		{"exec:test:", "should be {command}:{code}"},
+		{"exec::ok", "should be {command}:{code}"},
+	} {
+		err := adapter.Validate(tc.fetchID)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("unexpected error for %q: want=%q got=%v", tc.fetchID, tc.wantErr, err)
+		}
+	}
+}
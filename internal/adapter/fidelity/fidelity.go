@@ -7,6 +7,9 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/fundprice"
 )
 
 type FundData struct {
@@ -73,19 +76,32 @@ func (fd FundData) NetAssets() int64 {
 	return v
 }
 
+// DefaultBaseURL is the base URL of Fidelity Japan.
+const DefaultBaseURL = "https://www.fidelity.co.jp"
+
+// Client is a client of Fidelity Japan.
+type Client struct {
+	opts adapter.Options
+}
+
+// NewClient creates a new Client with options.
+func NewClient(opts adapter.Options) *Client {
+	return &Client{opts: opts}
+}
+
 func Get(ctx context.Context, id string) (*FundData, error) {
-	u := fmt.Sprintf("https://www.fidelity.co.jp/api/ce/fdh/FundData.json?id=%s&country=jp", id)
-	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
-	if err != nil {
-		return nil, err
-	}
-	res, err := http.DefaultClient.Do(req)
+	return NewClient(adapter.OptionsFor("fidelity")).Get(ctx, id)
+}
+
+func (c *Client) Get(ctx context.Context, id string) (*FundData, error) {
+	u := c.opts.URL(DefaultBaseURL, fmt.Sprintf("/api/ce/fdh/FundData.json?id=%s&country=jp", id))
+	res, err := c.opts.Get(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP failed with status code %d", res.StatusCode)
+		return nil, adapter.NewStatusError(res, fmt.Errorf("HTTP failed with status code %d", res.StatusCode))
 	}
 	var data map[string]FundData
 	err = json.NewDecoder(res.Body).Decode(&data)
@@ -99,3 +115,17 @@ func Get(ctx context.Context, id string) (*FundData, error) {
 	target.KeyID = id
 	return &target, nil
 }
+
+func init() {
+	adapter.Register(adapter.Scheme{
+		Name: "fidelity",
+		Desc: "Fidelity Japan",
+		Fetch: func(ctx context.Context, id string) (fundprice.Price, error) {
+			d, err := Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return d, nil
+		},
+	})
+}
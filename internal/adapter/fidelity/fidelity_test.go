@@ -2,12 +2,87 @@ package fidelity_test
 
 import (
 	"context"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/koron/funddb/internal/adapter/adaptertest"
 	"github.com/koron/funddb/internal/adapter/fidelity"
 )
 
+func TestClientGet(t *testing.T) {
+	c := fidelity.NewClient(adaptertest.NewServer(t, "application/json", map[string]adaptertest.Route{
+		"/api/ce/fdh/FundData.json?id=267002/F&country=jp": {Status: 200, File: "fidelity.json"},
+		"/api/ce/fdh/FundData.json?id=217001/F&country=jp": {Status: 200, File: "fidelity.json"},
+		"/api/ce/fdh/FundData.json?id=267007/F&country=jp": {Status: 200, File: "badprice.json"},
+		"/api/ce/fdh/FundData.json?id=503/F&country=jp":    {Status: 503, File: "fidelity.json"},
+	}))
+
+	t.Run("success", func(t *testing.T) {
+		d, err := c.Get(context.Background(), "267002/F")
+		if err != nil {
+			t.Fatal(err)
+		}
+		loc, err := time.LoadLocation("Japan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := d.Date(), time.Date(2024, 6, 24, 18, 0, 0, 0, loc); !got.Equal(want) {
+			t.Errorf("unmatched date: want=%s got=%s", want, got)
+		}
+		if got, want := d.ID(), "267002/F"; got != want {
+			t.Errorf("unmatched ID: want=%s got=%s", want, got)
+		}
+		if got, want := d.Price(), int64(27412); got != want {
+			t.Errorf("unmatched price: want=%d got=%d", want, got)
+		}
+		if got, want := d.NetAssets(), int64(122345678901); got != want {
+			t.Errorf("unmatched net assets: want=%d got=%d", want, got)
+		}
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		d, err := c.Get(context.Background(), "267007/F")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dt := d.Date(); !dt.IsZero() {
+			t.Errorf("date should be zero: %s", dt)
+		}
+		if p := d.Price(); p != -1 {
+			t.Errorf("price should be -1: %d", p)
+		}
+		if na := d.NetAssets(); na != -1 {
+			t.Errorf("net assets should be -1: %d", na)
+		}
+	})
+
+	for _, c0 := range []struct {
+		id      string
+		wantErr string
+	}{
+		{"217001/F", "fund data 217001/F not found"},
+		{"503/F", "HTTP failed with status code 503"},
+		{"404/F", "HTTP failed with status code 404"},
+	} {
+		_, err := c.Get(context.Background(), c0.id)
+		if err == nil {
+			t.Errorf("no errors for %q", c0.id)
+			continue
+		}
+		if !strings.Contains(err.Error(), c0.wantErr) {
+			t.Errorf("unexpected error for %q: want=%q got=%q", c0.id, c0.wantErr, err)
+		}
+	}
+}
+
+// TestGet accesses the production server. It runs only when FUNDDB_LIVE_TEST
+// is set.
 func TestGet(t *testing.T) {
+	if os.Getenv("FUNDDB_LIVE_TEST") == "" {
+		t.Skip("FUNDDB_LIVE_TEST is not set")
+	}
 	ctx := context.Background()
 
 	for _, name := range []string{
@@ -0,0 +1,450 @@
+// Package generic provides the "generic" scheme, which fetches prices from
+// providers declared in the config file. A fetch ID "generic:{provider}:{code}"
+// names a provider and a code of a fund for it.
+package generic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/fundprice"
+	"github.com/koron/funddb/internal/pricecsv"
+	"golang.org/x/net/html/charset"
+)
+
+// Types of responses.
+const (
+	TypeJSON = "json"
+	TypeHTML = "html"
+)
+
+// Field locates a value in a response, and describes its format.
+type Field struct {
+	// Path is a path to a value in JSON, like "data.nav[0].price".
+	Path string `json:"path,omitempty"`
+
+	// Selector is a CSS selector of an element in HTML. Text of the first
+	// matched element is used, or its attribute when Attr is given.
+	Selector string `json:"selector,omitempty"`
+	Attr     string `json:"attr,omitempty"`
+
+	// Pattern is a regular expression to extract a part of the value. The
+	// first group is used when it has groups.
+	Pattern string `json:"pattern,omitempty"`
+
+	// Format is a layout of dates for time.Parse, like "2006年01月02日", or
+	// "unix" and "unixmilli" for timestamps. Dates are in JST. Japanese
+	// formats are guessed when empty.
+	Format string `json:"format,omitempty"`
+
+	// Unit is a unit of numbers: 円, 千円, 百万円 or 億円. It is guessed by
+	// a suffix of the value when empty.
+	Unit string `json:"unit,omitempty"`
+
+	// rx is Pattern compiled by validate.
+	rx *regexp.Regexp
+}
+
+// IsZero reports whether f locates no values.
+func (f Field) IsZero() bool {
+	return f.Path == "" && f.Selector == ""
+}
+
+// validate checks f has required fields in valid formats, and compiles
+// Pattern.
+func (f *Field) validate(typ string) error {
+	switch typ {
+	case TypeJSON:
+		if f.Path == "" {
+			return errors.New("path is required")
+		}
+	case TypeHTML:
+		if f.Selector == "" {
+			return errors.New("selector is required")
+		}
+	}
+	if f.Pattern != "" && (f.rx == nil || f.rx.String() != f.Pattern) {
+		rx, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return err
+		}
+		f.rx = rx
+	}
+	if _, ok := pricecsv.Units[f.Unit]; f.Unit != "" && !ok {
+		return fmt.Errorf("unknown unit %q, available units are: 円|千円|百万円|億円", f.Unit)
+	}
+	return nil
+}
+
+// extract applies Pattern, which is compiled by validate, to a value.
+func (f Field) extract(s string) (string, error) {
+	if f.rx == nil {
+		return strings.TrimSpace(s), nil
+	}
+	sub := f.rx.FindStringSubmatch(s)
+	if sub == nil {
+		return "", fmt.Errorf("pattern %q doesn't match with %q", f.Pattern, s)
+	}
+	if len(sub) > 1 {
+		return strings.TrimSpace(sub[1]), nil
+	}
+	return strings.TrimSpace(sub[0]), nil
+}
+
+// parseDate parses a date in loc.
+func (f Field) parseDate(s string, loc *time.Location) (dataobj.Date, error) {
+	switch f.Format {
+	case "":
+		return pricecsv.ParseDate(s)
+	case "unix", "unixmilli":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return dataobj.Date{}, fmt.Errorf("invalid timestamp %q", s)
+		}
+		ti := time.Unix(n, 0)
+		if f.Format == "unixmilli" {
+			ti = time.UnixMilli(n)
+		}
+		return dataobj.DateFromTime(ti.In(loc)), nil
+	default:
+		// dates without zones are in loc, and others are converted to it.
+		ti, err := time.ParseInLocation(f.Format, s, loc)
+		if err != nil {
+			return dataobj.Date{}, err
+		}
+		return dataobj.DateFromTime(ti.In(loc)), nil
+	}
+}
+
+func (f Field) parseNumber(s string) (int64, error) {
+	// zero for an empty Unit, to guess it by a suffix.
+	return pricecsv.ParseNumber(s, pricecsv.Units[f.Unit])
+}
+
+// Provider is a declaration of a provider.
+type Provider struct {
+	// URL is a template of URLs, "{id}" in it is replaced with a code of a
+	// fund.
+	URL string `json:"url"`
+
+	// Type is a type of responses: "json" or "html".
+	Type string `json:"type"`
+
+	Date  Field `json:"date"`
+	Price Field `json:"price"`
+	// NetAssets is optional.
+	NetAssets Field `json:"net_assets,omitzero"`
+}
+
+// Validate checks p has required fields in valid formats, and compiles
+// patterns of fields.
+func (p *Provider) Validate() error {
+	if p.URL == "" {
+		return errors.New("url is required")
+	}
+	if p.Type != TypeJSON && p.Type != TypeHTML {
+		return fmt.Errorf("unknown type %q, available types are: json|html", p.Type)
+	}
+	if err := p.Date.validate(p.Type); err != nil {
+		return fmt.Errorf("date: %w", err)
+	}
+	if err := p.Price.validate(p.Type); err != nil {
+		return fmt.Errorf("price: %w", err)
+	}
+	if !p.NetAssets.IsZero() {
+		if err := p.NetAssets.validate(p.Type); err != nil {
+			return fmt.Errorf("net_assets: %w", err)
+		}
+	}
+	return nil
+}
+
+// URLFor composes a URL for a code of a fund.
+func (p Provider) URLFor(code string) string {
+	return strings.ReplaceAll(p.URL, "{id}", url.PathEscape(code))
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// SetProviders replaces all declared providers.
+func SetProviders(m map[string]Provider) {
+	mu.Lock()
+	providers = m
+	mu.Unlock()
+}
+
+// LookupProvider finds a declared provider by name.
+func LookupProvider(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+type Data struct {
+	id        string
+	date      time.Time
+	price     int64
+	netAssets int64
+}
+
+func (d Data) Scheme() string {
+	return "generic"
+}
+
+func (d Data) ID() string {
+	return d.id
+}
+
+func (d Data) Date() time.Time {
+	return d.date
+}
+
+func (d Data) Price() int64 {
+	return d.price
+}
+
+func (d Data) NetAssets() int64 {
+	return d.netAssets
+}
+
+// Client is a client for declared providers.
+type Client struct {
+	opts adapter.Options
+}
+
+// NewClient creates a new Client with options. BaseURL of options is not
+// used, as URLs are given by providers.
+func NewClient(opts adapter.Options) *Client {
+	return &Client{opts: opts}
+}
+
+// parseID parses an ID in fetch IDs without the scheme: "{provider}:{code}",
+// and finds the declared provider.
+func parseID(id string) (Provider, string, error) {
+	name, code, ok := strings.Cut(id, ":")
+	if !ok || name == "" || code == "" {
+		return Provider{}, "", fmt.Errorf("invalid ID %q, which should be {provider}:{code}", id)
+	}
+	p, ok := LookupProvider(name)
+	if !ok {
+		return Provider{}, "", fmt.Errorf("unknown provider %q, which should be declared in \"providers\" of the config file", name)
+	}
+	return p, code, nil
+}
+
+// Get retrieves the latest price for a fetch ID without the scheme:
+// "{provider}:{code}".
+func Get(ctx context.Context, id string) (*Data, error) {
+	p, code, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	d, err := NewClient(adapter.OptionsFor("generic")).Get(ctx, p, code)
+	if err != nil {
+		return nil, err
+	}
+	d.id = id
+	return d, nil
+}
+
+// Get retrieves the latest price for a code of a fund from a provider.
+func (c *Client) Get(ctx context.Context, p Provider, code string) (*Data, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		return nil, err
+	}
+	u := p.URLFor(code)
+	res, err := c.opts.Get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, adapter.NewStatusError(res, fmt.Errorf("failed HTTP with %d for: %q", res.StatusCode, u))
+	}
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var lookup func(f Field) (string, error)
+	switch p.Type {
+	case TypeJSON:
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		lookup = func(f Field) (string, error) {
+			return lookupJSON(doc, f.Path)
+		}
+	case TypeHTML:
+		b, err := decodeHTML(b, res.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		lookup = func(f Field) (string, error) {
+			return lookupHTML(doc, f.Selector, f.Attr)
+		}
+	}
+	value := func(name string, f Field) (string, error) {
+		s, err := lookup(f)
+		if err == nil {
+			s, err = f.extract(s)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		return s, nil
+	}
+
+	var (
+		d    Data
+		errs []error
+	)
+	if s, err := value("date", p.Date); err != nil {
+		errs = append(errs, err)
+	} else if date, err := p.Date.parseDate(s, loc); err != nil {
+		errs = append(errs, fmt.Errorf("date: %w", err))
+	} else {
+		d.date = date.Time(loc).Add(time.Hour * 18)
+	}
+	if s, err := value("price", p.Price); err != nil {
+		errs = append(errs, err)
+	} else if d.price, err = p.Price.parseNumber(s); err != nil {
+		errs = append(errs, fmt.Errorf("price: %w", err))
+	}
+	if !p.NetAssets.IsZero() {
+		if s, err := value("net_assets", p.NetAssets); err != nil {
+			errs = append(errs, err)
+		} else if d.netAssets, err = p.NetAssets.parseNumber(s); err != nil {
+			errs = append(errs, fmt.Errorf("net_assets: %w", err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	d.id = code
+	return &d, nil
+}
+
+// lookupJSON finds a value in decoded JSON by a path like "data.nav[0].price".
+// A leading "$." is allowed, and indexes of arrays can be written as "nav.0".
+func lookupJSON(doc any, path string) (string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	var keys []string
+	for _, seg := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(seg, "[")
+		if name != "" {
+			keys = append(keys, name)
+		}
+		for rest != "" {
+			idx, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return "", fmt.Errorf("invalid path %q", path)
+			}
+			keys = append(keys, idx)
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	v := doc
+	for i, k := range keys {
+		ok := false
+		switch x := v.(type) {
+		case map[string]any:
+			v, ok = x[k]
+		case []any:
+			if n, err := strconv.Atoi(k); err == nil && n >= 0 && n < len(x) {
+				v, ok = x[n], true
+			}
+		}
+		if !ok {
+			return "", fmt.Errorf("not found %q in %q", strings.Join(keys[:i+1], "."), path)
+		}
+	}
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case json.Number:
+		return x.String(), nil
+	default:
+		return "", fmt.Errorf("not a string or a number at %q: %v", path, v)
+	}
+}
+
+// decodeHTML decodes a HTML into UTF-8 by its charset, which is given by a
+// Content-Type header or a meta tag. A HTML without any charsets is treated as
+// UTF-8 when it is valid as UTF-8.
+func decodeHTML(b []byte, contentType string) ([]byte, error) {
+	e, name, certain := charset.DetermineEncoding(b, contentType)
+	if name == "utf-8" || (!certain && utf8.Valid(b)) {
+		return b, nil
+	}
+	b, err := e.NewDecoder().Bytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode HTML as %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// lookupHTML finds text or an attribute of the first element matched with
+// a selector.
+func lookupHTML(doc *goquery.Document, selector, attr string) (string, error) {
+	s := doc.Find(selector).First()
+	if s.Length() == 0 {
+		return "", fmt.Errorf("not found %q", selector)
+	}
+	if attr == "" {
+		return s.Text(), nil
+	}
+	v, ok := s.Attr(attr)
+	if !ok {
+		return "", fmt.Errorf("not found attribute %q of %q", attr, selector)
+	}
+	return v, nil
+}
+
+func init() {
+	adapter.Register(adapter.Scheme{
+		Name: "generic",
+		Desc: "Providers declared in the config file ({provider}:{code})",
+		Fetch: func(ctx context.Context, id string) (fundprice.Price, error) {
+			d, err := Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return d, nil
+		},
+		CheckID: func(id string) error {
+			_, _, err := parseID(id)
+			return err
+		},
+	})
+}
@@ -0,0 +1,257 @@
+package generic_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/adapter/generic"
+)
+
+// newServer starts a server which responds files in testdata for paths. A
+// "charset" query adds a charset to Content-Type header.
+func newServer(t *testing.T) (*generic.Client, string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := filepath.Base(r.URL.Path)
+		b, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		ct := "text/html"
+		if filepath.Ext(name) == ".json" {
+			ct = "application/json"
+		}
+		if cs := r.URL.Query().Get("charset"); cs != "" {
+			ct += "; charset=" + cs
+		}
+		w.Header().Set("Content-Type", ct)
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return generic.NewClient(adapter.Options{HTTPClient: srv.Client()}), srv.URL
+}
+
+func checkData(t *testing.T, d *generic.Data, price, netAssets int64) {
+	t.Helper()
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.Date(), time.Date(2024, 6, 24, 18, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("unmatched date: want=%s got=%s", want, got)
+	}
+	if got := d.Price(); got != price {
+		t.Errorf("unmatched price: want=%d got=%d", price, got)
+	}
+	if got := d.NetAssets(); got != netAssets {
+		t.Errorf("unmatched net assets: want=%d got=%d", netAssets, got)
+	}
+}
+
+func TestClientGetJSON(t *testing.T) {
+	c, base := newServer(t)
+	p := generic.Provider{
+		URL:       base + "/{id}.json",
+		Type:      generic.TypeJSON,
+		Date:      generic.Field{Path: "data.nav[0].date"},
+		Price:     generic.Field{Path: "$.data.nav.0.price"},
+		NetAssets: generic.Field{Path: "data.nav[0].assets"},
+	}
+	d, err := c.Get(context.Background(), p, "fund")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkData(t, d, 12345, 45_678_900_000)
+	if d.ID() != "fund" {
+		t.Errorf("unmatched ID: %s", d.ID())
+	}
+
+	p.Date = generic.Field{Path: "data.updated", Format: "unixmilli"}
+	p.NetAssets = generic.Field{}
+	d, err = c.Get(context.Background(), p, "fund")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkData(t, d, 12345, 0)
+
+	for _, f := range []generic.Field{
+		{Path: "data.nav[0].date", Format: "2006/01/02"},
+		// a date in UTC is converted to JST.
+		{Path: "data.timestamp", Format: time.RFC3339},
+	} {
+		p.Date = f
+		d, err = c.Get(context.Background(), p, "fund")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkData(t, d, 12345, 0)
+	}
+}
+
+func TestClientGetHTML(t *testing.T) {
+	c, base := newServer(t)
+	p := generic.Provider{
+		URL:       base + "/{id}.html",
+		Type:      generic.TypeHTML,
+		Date:      generic.Field{Selector: ".summary .date", Format: "基準日: 2006年01月02日"},
+		Price:     generic.Field{Selector: "dd.price"},
+		NetAssets: generic.Field{Selector: "dd.assets", Attr: "data-value", Unit: "百万円"},
+	}
+	d, err := c.Get(context.Background(), p, "fund")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkData(t, d, 12345, 45_678_000_000)
+
+	p.Date = generic.Field{Selector: ".date", Pattern: `(\d+年\d+月\d+日)`}
+	p.NetAssets = generic.Field{Selector: "dd.assets"}
+	d, err = c.Get(context.Background(), p, "fund")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkData(t, d, 12345, 45_678_000_000)
+}
+
+func TestClientGetHTMLCharset(t *testing.T) {
+	c, base := newServer(t)
+	for _, tc := range []struct {
+		name string
+		url  string
+		code string
+	}{
+		{"meta tag", "/{id}.html", "fund_sjis"},
+		{"Content-Type header", "/{id}.html?charset=EUC-JP", "fund_eucjp"},
+		{"UTF-8 meta tag", "/{id}.html", "fund"},
+		{"no charsets", "/{id}.html", "fund_nocharset"},
+	} {
+		p := generic.Provider{
+			URL:       base + tc.url,
+			Type:      generic.TypeHTML,
+			Date:      generic.Field{Selector: ".summary .date", Format: "基準日: 2006年01月02日"},
+			Price:     generic.Field{Selector: "dd.price"},
+			NetAssets: generic.Field{Selector: "dd.assets", Unit: "百万円"},
+		}
+		d, err := c.Get(context.Background(), p, tc.code)
+		if err != nil {
+			t.Errorf("failed for %s: %v", tc.name, err)
+			continue
+		}
+		checkData(t, d, 12345, 45_678_000_000)
+	}
+}
+
+func TestClientGetError(t *testing.T) {
+	c, base := newServer(t)
+	for _, tc := range []struct {
+		name     string
+		p        generic.Provider
+		code     string
+		wantErrs []string
+	}{
+		{
+			"not found",
+			generic.Provider{URL: base + "/{id}.json", Type: "json", Date: generic.Field{Path: "data.date"}, Price: generic.Field{Path: "data.nav[2].price"}},
+			"fund",
+			[]string{`date: not found "data.date"`, `price: not found "data.nav.2"`},
+		},
+		{
+			"bad values",
+			generic.Provider{URL: base + "/{id}.html", Type: "html", Date: generic.Field{Selector: ".price"}, Price: generic.Field{Selector: ".date"}},
+			"fund",
+			[]string{"date: invalid date", "price: invalid number"},
+		},
+		{
+			"no pattern match",
+			generic.Provider{URL: base + "/{id}.html", Type: "html", Date: generic.Field{Selector: ".date", Pattern: `\d+/\d+/\d+`}, Price: generic.Field{Selector: ".price"}},
+			"fund",
+			[]string{"date: pattern"},
+		},
+		{
+			"HTTP status",
+			generic.Provider{URL: base + "/{id}.json", Type: "json", Date: generic.Field{Path: "date"}, Price: generic.Field{Path: "price"}},
+			"missing",
+			[]string{"failed HTTP with 404"},
+		},
+		{
+			"invalid provider",
+			generic.Provider{URL: base + "/{id}.json", Type: "xml"},
+			"fund",
+			[]string{"unknown type"},
+		},
+	} {
+		_, err := c.Get(context.Background(), tc.p, tc.code)
+		if err == nil {
+			t.Errorf("no errors for %q", tc.name)
+			continue
+		}
+		for _, want := range tc.wantErrs {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("unexpected error for %q: want=%q got=%q", tc.name, want, err)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ok := generic.Provider{URL: "https://example.com/{id}", Type: "json", Date: generic.Field{Path: "date"}, Price: generic.Field{Path: "price", Pattern: `([0-9,]+)`}}
+	// edits of validated providers are validated again.
+	if err := ok.Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for _, tc := range []struct {
+		name string
+		edit func(p *generic.Provider)
+	}{
+		{"no URL", func(p *generic.Provider) { p.URL = "" }},
+		{"no price", func(p *generic.Provider) { p.Price = generic.Field{} }},
+		{"selector for JSON", func(p *generic.Provider) { p.Date = generic.Field{Selector: ".date"} }},
+		{"bad pattern", func(p *generic.Provider) { p.Price.Pattern = "(" }},
+		{"bad unit", func(p *generic.Provider) { p.NetAssets = generic.Field{Path: "assets", Unit: "ドル"} }},
+	} {
+		p := ok
+		tc.edit(&p)
+		if err := p.Validate(); err == nil {
+			t.Errorf("no errors for %q", tc.name)
+		}
+	}
+}
+
+func TestGetUnknownProvider(t *testing.T) {
+	generic.SetProviders(nil)
+	_, err := adapter.Fetch(context.Background(), "generic:unknown:123")
+	if err == nil || !strings.Contains(err.Error(), `unknown provider "unknown"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckID(t *testing.T) {
+	generic.SetProviders(map[string]generic.Provider{
+		"example": {URL: "https://example.com/{id}", Type: "json", Date: generic.Field{Path: "date"}, Price: generic.Field{Path: "price"}},
+	})
+	t.Cleanup(func() { generic.SetProviders(nil) })
+	if err := adapter.Validate("generic:example:123"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for _, tc := range []struct {
+		fetchID string
+		wantErr string
+	}{
+		{"generic:exmaple:123", `unknown provider "exmaple"`},
+		{"generic:example", "should be {provider}:{code}"},
+		{"generic:example:", "should be {provider}:{code}"},
+		{"generic::123", "should be {provider}:{code}"},
+	} {
+		err := adapter.Validate(tc.fetchID)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("unexpected error for %q: want=%q got=%v", tc.fetchID, tc.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options holds HTTP settings for adapters.
+type Options struct {
+	// HTTPClient is used to send requests. http.DefaultClient is used when
+	// nil.
+	HTTPClient *http.Client
+
+	// BaseURL overrides the production URL of a provider, e.g.
+	// "http://127.0.0.1:8080". An adapter's default is used when empty.
+	BaseURL string
+
+	// UserAgent is sent as User-Agent header when not empty.
+	UserAgent string
+
+	// Timeout limits the time for a request when positive.
+	Timeout time.Duration
+}
+
+// merge fills zero fields of o with ones of def.
+func (o Options) merge(def Options) Options {
+	if o.HTTPClient == nil {
+		o.HTTPClient = def.HTTPClient
+	}
+	if o.BaseURL == "" {
+		o.BaseURL = def.BaseURL
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = def.UserAgent
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = def.Timeout
+	}
+	return o
+}
+
+// URL composes a URL from BaseURL (or defaultBase when BaseURL is empty) and
+// path.
+func (o Options) URL(defaultBase, path string) string {
+	base := o.BaseURL
+	if base == "" {
+		base = defaultBase
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+// Get sends a GET request to u with the options.
+func (o Options) Get(ctx context.Context, u string) (*http.Response, error) {
+	client := o.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if o.Timeout > 0 {
+		c := *client
+		c.Timeout = o.Timeout
+		client = &c
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if o.UserAgent != "" {
+		req.Header.Set("User-Agent", o.UserAgent)
+	}
+	return client.Do(req)
+}
+
+var (
+	optsMu      sync.RWMutex
+	defaultOpts Options
+	schemeOpts  = map[string]Options{}
+)
+
+// SetDefaultOptions sets options used by all schemes.
+func SetDefaultOptions(opts Options) {
+	optsMu.Lock()
+	defaultOpts = opts
+	optsMu.Unlock()
+}
+
+// SetOptions sets options for a scheme. Zero fields fall back to ones set by
+// SetDefaultOptions.
+func SetOptions(scheme string, opts Options) {
+	optsMu.Lock()
+	schemeOpts[scheme] = opts
+	optsMu.Unlock()
+}
+
+// OptionsFor returns options for a scheme.
+func OptionsFor(scheme string) Options {
+	optsMu.RLock()
+	defer optsMu.RUnlock()
+	return schemeOpts[scheme].merge(defaultOpts)
+}
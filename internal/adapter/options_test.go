@@ -0,0 +1,64 @@
+package adapter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+)
+
+func TestOptionsGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(r.Header.Get("User-Agent")))
+	}))
+	defer srv.Close()
+
+	opts := adapter.Options{
+		HTTPClient: srv.Client(),
+		BaseURL:    srv.URL + "/",
+		UserAgent:  "funddb-test",
+		Timeout:    50 * time.Millisecond,
+	}
+	u := opts.URL("https://example.com", "/foo")
+	if want := srv.URL + "/foo"; u != want {
+		t.Errorf("unmatch URL: want=%s got=%s", want, u)
+	}
+	res, err := opts.Get(context.Background(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var b [64]byte
+	n, _ := res.Body.Read(b[:])
+	if got := string(b[:n]); got != "funddb-test" {
+		t.Errorf("unmatch User-Agent: want=funddb-test got=%s", got)
+	}
+
+	_, err = opts.Get(context.Background(), opts.URL("", "/slow"))
+	if err == nil {
+		t.Error("no timeout errors")
+	}
+}
+
+func TestOptionsFor(t *testing.T) {
+	adapter.SetDefaultOptions(adapter.Options{UserAgent: "default", Timeout: time.Second})
+	adapter.SetOptions("foo", adapter.Options{BaseURL: "http://127.0.0.1", UserAgent: "foo"})
+	defer adapter.SetDefaultOptions(adapter.Options{})
+
+	got := adapter.OptionsFor("foo")
+	want := adapter.Options{BaseURL: "http://127.0.0.1", UserAgent: "foo", Timeout: time.Second}
+	if got != want {
+		t.Errorf("unmatch options for foo: want=%+v got=%+v", want, got)
+	}
+	got = adapter.OptionsFor("bar")
+	want = adapter.Options{UserAgent: "default", Timeout: time.Second}
+	if got != want {
+		t.Errorf("unmatch options for bar: want=%+v got=%+v", want, got)
+	}
+}
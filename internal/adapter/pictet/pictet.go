@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/fundprice"
 )
 
 type Data struct {
@@ -68,19 +70,32 @@ func parseNetAssets(s string) (int64, error) {
 	return n * 1_000_000, err
 }
 
+// DefaultBaseURL is the base URL of Pictet Japan.
+const DefaultBaseURL = "https://www.pictet.co.jp"
+
+// Client is a client of Pictet Japan.
+type Client struct {
+	opts adapter.Options
+}
+
+// NewClient creates a new Client with options.
+func NewClient(opts adapter.Options) *Client {
+	return &Client{opts: opts}
+}
+
 func Get(ctx context.Context, name string) (*Data, error) {
-	u := fmt.Sprintf("https://www.pictet.co.jp/fund/%s.html", name)
-	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
-	if err != nil {
-		return nil, err
-	}
-	res, err := http.DefaultClient.Do(req)
+	return NewClient(adapter.OptionsFor("pictet")).Get(ctx, name)
+}
+
+func (c *Client) Get(ctx context.Context, name string) (*Data, error) {
+	u := c.opts.URL(DefaultBaseURL, fmt.Sprintf("/fund/%s.html", name))
+	res, err := c.opts.Get(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed HTTP with %d for: %q", res.StatusCode, u)
+		return nil, adapter.NewStatusError(res, fmt.Errorf("failed HTTP with %d for: %q", res.StatusCode, u))
 	}
 
 	doc, err := goquery.NewDocumentFromReader(res.Body)
@@ -142,3 +157,17 @@ func Get(ctx context.Context, name string) (*Data, error) {
 	d.id = name
 	return &d, nil
 }
+
+func init() {
+	adapter.Register(adapter.Scheme{
+		Name: "pictet",
+		Desc: "Pictet Japan (fund page name)",
+		Fetch: func(ctx context.Context, id string) (fundprice.Price, error) {
+			d, err := Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return d, nil
+		},
+	})
+}
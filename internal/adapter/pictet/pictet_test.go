@@ -2,12 +2,74 @@ package pictet_test
 
 import (
 	"context"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/koron/funddb/internal/adapter/adaptertest"
 	"github.com/koron/funddb/internal/adapter/pictet"
 )
 
+func TestClientGet(t *testing.T) {
+	c := pictet.NewClient(adaptertest.NewServer(t, "text/html; charset=UTF-8", map[string]adaptertest.Route{
+		"/fund/prembrand.html": {Status: 200, File: "prembrand.html"},
+		"/fund/gloin.html":     {Status: 200, File: "noprice.html"},
+		"/fund/quattro.html":   {Status: 200, File: "badvalues.html"},
+		"/fund/closed.html":    {Status: 500, File: "prembrand.html"},
+	}))
+
+	t.Run("success", func(t *testing.T) {
+		d, err := c.Get(context.Background(), "prembrand")
+		if err != nil {
+			t.Fatal(err)
+		}
+		loc, err := time.LoadLocation("Japan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := d.Date(), time.Date(2024, 6, 24, 18, 0, 0, 0, loc); !got.Equal(want) {
+			t.Errorf("unmatched date: want=%s got=%s", want, got)
+		}
+		if got, want := d.ID(), "prembrand"; got != want {
+			t.Errorf("unmatched ID: want=%s got=%s", want, got)
+		}
+		if got, want := d.Price(), int64(12345); got != want {
+			t.Errorf("unmatched price: want=%d got=%d", want, got)
+		}
+		if got, want := d.NetAssets(), int64(45_678_000_000); got != want {
+			t.Errorf("unmatched net assets: want=%d got=%d", want, got)
+		}
+	})
+
+	for _, c0 := range []struct {
+		name     string
+		wantErrs []string
+	}{
+		{"gloin", []string{"not found price"}},
+		{"quattro", []string{"cannot parse", "expected integer", "not found date", "not found price", "not found net assets"}},
+		{"closed", []string{"failed HTTP with 500"}},
+		{"unknown", []string{"failed HTTP with 404"}},
+	} {
+		_, err := c.Get(context.Background(), c0.name)
+		if err == nil {
+			t.Errorf("no errors for %q", c0.name)
+			continue
+		}
+		for _, want := range c0.wantErrs {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("unexpected error for %q: want=%q got=%q", c0.name, want, err)
+			}
+		}
+	}
+}
+
+// TestGet accesses the production server. It runs only when FUNDDB_LIVE_TEST
+// is set.
 func TestGet(t *testing.T) {
+	if os.Getenv("FUNDDB_LIVE_TEST") == "" {
+		t.Skip("FUNDDB_LIVE_TEST is not set")
+	}
 	ctx := context.Background()
 
 	for _, name := range []string{
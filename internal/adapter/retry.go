@@ -0,0 +1,186 @@
+package adapter
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/koron/funddb/internal/fundprice"
+)
+
+// StatusError is an error for an unexpected HTTP status.
+type StatusError struct {
+	StatusCode int
+
+	// RetryAfter is a duration given by Retry-After header, or zero.
+	RetryAfter time.Duration
+
+	Err error
+}
+
+// NewStatusError creates a StatusError for a response with an error which
+// describes it.
+func NewStatusError(res *http.Response, err error) *StatusError {
+	return &StatusError{
+		StatusCode: res.StatusCode,
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		Err:        err,
+	}
+}
+
+func (err *StatusError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *StatusError) Unwrap() error {
+	return err.Err
+}
+
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return 0
+		}
+		return time.Duration(n) * time.Second
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Retryable classifies an error. It returns true for errors which may be
+// resolved by retrying: timeouts, failures to dial or read like refused,
+// reset or unreachable connections, unexpected EOFs, 5xx and 429 statuses,
+// and errors which report Temporary() true. A duration required by a server
+// to wait is returned too, when available. Other errors, like unknown hosts,
+// invalid certificates, unsupported protocols, 4xx statuses or parse errors,
+// are permanent.
+func Retryable(err error) (bool, time.Duration) {
+	if err == nil {
+		return false, 0
+	}
+	if errors.Is(err, context.Canceled) {
+		return false, 0
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		if se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500 {
+			return true, se.RetryAfter
+		}
+		return false, 0
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return true, 0
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound && (dnsErr.IsTimeout || dnsErr.IsTemporary), 0
+	}
+	if isCertificateError(err) {
+		return false, 0
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) && (oe.Op == "dial" || oe.Op == "read") {
+		return true, 0
+	}
+	// other network errors, like unsupported protocols, are permanent.
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return ne.Timeout(), 0
+	}
+	var te interface{ Temporary() bool }
+	if errors.As(err, &te) {
+		return te.Temporary(), 0
+	}
+	return false, 0
+}
+
+// isCertificateError reports whether an error is a failure of verifying
+// certificates of a server.
+func isCertificateError(err error) bool {
+	var (
+		cve *tls.CertificateVerificationError
+		uae x509.UnknownAuthorityError
+		he  x509.HostnameError
+		cie x509.CertificateInvalidError
+	)
+	return errors.As(err, &cve) || errors.As(err, &uae) || errors.As(err, &he) || errors.As(err, &cie)
+}
+
+// RetryPolicy retries an operation on retryable errors with exponential
+// backoff and jitter.
+type RetryPolicy struct {
+	// Retries is the maximum number of retries. No retries when zero.
+	Retries int
+
+	// Wait is a base duration to wait before the first retry. It is doubled
+	// for each retry.
+	Wait time.Duration
+
+	// MaxWait limits a duration to wait, when positive.
+	MaxWait time.Duration
+}
+
+// Do calls fn until it succeeds, fails with a permanent error, or retries
+// run out.
+func (rp RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	for n := 0; ; n++ {
+		err := fn(ctx)
+		if err == nil || n >= rp.Retries {
+			return err
+		}
+		ok, after := Retryable(err)
+		if !ok {
+			return err
+		}
+		wait := max(rp.backoff(n), after)
+		if rp.MaxWait > 0 {
+			wait = min(wait, rp.MaxWait)
+		}
+		log.Printf("retry %d/%d after %s: %v", n+1, rp.Retries, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a duration to wait before n-th retry (0 origin), which is
+// between a half and full of Wait * 2^n.
+func (rp RetryPolicy) backoff(n int) time.Duration {
+	d := rp.Wait << min(n, 16)
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// Fetch retrieves the latest price for a fetch ID with the retry policy.
+func (rp RetryPolicy) Fetch(ctx context.Context, fetchID string) (p fundprice.Price, err error) {
+	err = rp.Do(ctx, func(ctx context.Context) error {
+		var err error
+		p, err = Fetch(ctx, fetchID)
+		return err
+	})
+	return p, err
+}
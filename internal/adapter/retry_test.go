@@ -0,0 +1,141 @@
+package adapter_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+)
+
+type temporaryError bool
+
+func (err temporaryError) Error() string {
+	return fmt.Sprintf("temporary=%t", bool(err))
+}
+
+func (err temporaryError) Temporary() bool {
+	return bool(err)
+}
+
+func TestRetryable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	tooMany := adapter.NewStatusError(res, errors.New("too many"))
+
+	statusError := func(code int) error {
+		return &adapter.StatusError{StatusCode: code, Err: fmt.Errorf("status %d", code)}
+	}
+	_, dialErr := http.Get("http://127.0.0.1:0/")
+	_, schemeErr := http.Get("ftp://127.0.0.1/")
+	// urlError wraps an error like errors from http.Client.
+	urlError := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com/", Err: err}
+	}
+	opError := func(op string, err error) error {
+		return urlError(&net.OpError{Op: op, Net: "tcp", Err: err})
+	}
+
+	for i, c := range []struct {
+		err       error
+		want      bool
+		wantAfter time.Duration
+	}{
+		{nil, false, 0},
+		{tooMany, true, 7 * time.Second},
+		{fmt.Errorf("wrapped: %w", tooMany), true, 7 * time.Second},
+		{statusError(500), true, 0},
+		{statusError(503), true, 0},
+		{statusError(404), false, 0},
+		{statusError(400), false, 0},
+		{dialErr, true, 0},
+		{opError("dial", os.NewSyscallError("connect", syscall.ECONNREFUSED)), true, 0},
+		{opError("read", os.NewSyscallError("read", syscall.ECONNRESET)), true, 0},
+		{urlError(os.ErrDeadlineExceeded), true, 0},
+		{urlError(io.ErrUnexpectedEOF), true, 0},
+		{opError("dial", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}), false, 0},
+		{opError("dial", &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}), true, 0},
+		{opError("dial", &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}), true, 0},
+		{urlError(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), false, 0},
+		{urlError(x509.HostnameError{Certificate: &x509.Certificate{}, Host: "example.com"}), false, 0},
+		{urlError(x509.CertificateInvalidError{Cert: &x509.Certificate{}, Reason: x509.Expired}), false, 0},
+		{schemeErr, false, 0},
+		{opError("dial", os.NewSyscallError("connect", syscall.ENETUNREACH)), true, 0},
+		{opError("dial", os.NewSyscallError("connect", syscall.EHOSTUNREACH)), true, 0},
+		{opError("read", errors.New("use of closed network connection")), true, 0},
+		{context.DeadlineExceeded, true, 0},
+		{context.Canceled, false, 0},
+		{errors.New("failed to parse JSON"), false, 0},
+		{fmt.Errorf("wrapped: %w", temporaryError(true)), true, 0},
+		{temporaryError(false), false, 0},
+	} {
+		got, after := adapter.Retryable(c.err)
+		if got != c.want || after != c.wantAfter {
+			t.Errorf("#%d unmatch for %v: want=(%t, %s) got=(%t, %s)", i, c.err, c.want, c.wantAfter, got, after)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	rp := adapter.RetryPolicy{Retries: 3, Wait: time.Millisecond}
+
+	var n int
+	err := rp.Do(context.Background(), func(ctx context.Context) error {
+		n++
+		if n < 3 {
+			return &adapter.StatusError{StatusCode: 503, Err: errors.New("unavailable")}
+		}
+		return nil
+	})
+	if err != nil || n != 3 {
+		t.Errorf("should succeed at 3rd call: n=%d err=%v", n, err)
+	}
+
+	n = 0
+	err = rp.Do(context.Background(), func(ctx context.Context) error {
+		n++
+		return &adapter.StatusError{StatusCode: 503, Err: errors.New("unavailable")}
+	})
+	if err == nil || n != 4 {
+		t.Errorf("should fail after 3 retries: n=%d err=%v", n, err)
+	}
+
+	n = 0
+	err = rp.Do(context.Background(), func(ctx context.Context) error {
+		n++
+		return errors.New("permanent")
+	})
+	if err == nil || n != 1 {
+		t.Errorf("should not retry permanent errors: n=%d err=%v", n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n = 0
+	err = adapter.RetryPolicy{Retries: 3, Wait: time.Hour}.Do(ctx, func(ctx context.Context) error {
+		n++
+		cancel()
+		return &adapter.StatusError{StatusCode: 503, Err: errors.New("unavailable")}
+	})
+	if !errors.Is(err, context.Canceled) || n != 1 {
+		t.Errorf("should stop by cancel: n=%d err=%v", n, err)
+	}
+}
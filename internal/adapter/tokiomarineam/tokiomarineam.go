@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/fundprice"
 )
 
 // Should implement fundprice.Price
@@ -61,6 +64,35 @@ func (f FundInfo) NetAssets() int64 {
 	return f.Asset
 }
 
+// Distributions returns recent distributions, which have non-zero amount.
+func (f FundInfo) Distributions() []fundprice.Distribution {
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	var list []fundprice.Distribution
+	for _, d := range []struct {
+		dt  string
+		div int64
+	}{
+		{f.DivDt1, f.Div1},
+		{f.DivDt2, f.Div2},
+		{f.DivDt3, f.Div3},
+	} {
+		if d.dt == "" || d.div == 0 {
+			continue
+		}
+		ti, err := time.ParseInLocation("2006/01/02", d.dt, loc)
+		if err != nil {
+			log.Printf("invalid distribution date %s: %s", d.dt, err)
+			continue
+		}
+		list = append(list, fundprice.Distribution{ExDate: ti, Amount: d.div})
+	}
+	return list
+}
+
 type Hansya struct {
 	ToriKbn string `json:"ToriKbn"`
 	Type    string `json:"Type"`
@@ -70,17 +102,30 @@ type Hansya struct {
 	Nm      string `json:"Nm"`
 }
 
+// DefaultBaseURL is the base URL of the Tokio Marine Asset Management API.
+const DefaultBaseURL = "https://api.tokiomarineam.co.jp"
+
+// Client is a client of the Tokio Marine Asset Management API.
+type Client struct {
+	opts adapter.Options
+}
+
+// NewClient creates a new Client with options.
+func NewClient(opts adapter.Options) *Client {
+	return &Client{opts: opts}
+}
+
 func Get(ctx context.Context, fundId string, dummy *string) (*FundInfo, error) {
+	return NewClient(adapter.OptionsFor("tokiomarineam")).Get(ctx, fundId, dummy)
+}
+
+func (c *Client) Get(ctx context.Context, fundId string, dummy *string) (*FundInfo, error) {
 	// Compose and make a GET request.
-	u := fmt.Sprintf("https://api.tokiomarineam.co.jp/hp/funds?FundId=%s", url.QueryEscape(fundId))
+	u := c.opts.URL(DefaultBaseURL, fmt.Sprintf("/hp/funds?FundId=%s", url.QueryEscape(fundId)))
 	if dummy != nil {
 		u += "&_=" + url.QueryEscape(*dummy)
 	}
-	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
-	if err != nil {
-		return nil, err
-	}
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.opts.Get(ctx, u)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +137,7 @@ func Get(ctx context.Context, fundId string, dummy *string) (*FundInfo, error) {
 		} else {
 			log.Printf("[INFO] error:%d reponse:\n%s", res.StatusCode, string(all))
 		}
-		return nil, fmt.Errorf("failed HTTP with %d for: %q", res.StatusCode, u)
+		return nil, adapter.NewStatusError(res, fmt.Errorf("failed HTTP with %d for: %q", res.StatusCode, u))
 	}
 
 	var data FundInfo
@@ -104,3 +149,17 @@ func Get(ctx context.Context, fundId string, dummy *string) (*FundInfo, error) {
 
 	return &data, nil
 }
+
+func init() {
+	adapter.Register(adapter.Scheme{
+		Name: "tokiomarineam",
+		Desc: "Tokio Marine Asset Management",
+		Fetch: func(ctx context.Context, id string) (fundprice.Price, error) {
+			d, err := Get(ctx, id, nil)
+			if err != nil {
+				return nil, err
+			}
+			return d, nil
+		},
+	})
+}
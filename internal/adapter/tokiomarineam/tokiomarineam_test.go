@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/koron/funddb/internal/adapter/adaptertest"
 	"github.com/koron/funddb/internal/adapter/tokiomarineam"
+	"github.com/koron/funddb/internal/fundprice"
 )
 
 func TestDecode(t *testing.T) {
@@ -207,9 +210,84 @@ func TestDecode(t *testing.T) {
 			t.Errorf("unmatch Date: want=%s got=%s", want, got)
 		}
 	})
+	t.Run("Distributions", func(t *testing.T) {
+		loc, err := time.LoadLocation("Japan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []fundprice.Distribution{
+			{ExDate: time.Date(2024, 4, 22, 0, 0, 0, 0, loc), Amount: 500},
+			{ExDate: time.Date(2023, 10, 20, 0, 0, 0, 0, loc), Amount: 500},
+			{ExDate: time.Date(2023, 4, 20, 0, 0, 0, 0, loc), Amount: 500},
+		}
+		if d := cmp.Diff(want, data.Distributions()); d != "" {
+			t.Errorf("unmatch Distributions: -want +got\n%s", d)
+		}
+	})
 }
 
+func TestClientGet(t *testing.T) {
+	c := tokiomarineam.NewClient(adaptertest.NewServer(t, "application/json", map[string]adaptertest.Route{
+		"/hp/funds?FundId=635333":          {Status: 200, File: "tokiomarineam.json"},
+		"/hp/funds?FundId=635333&_=123456": {Status: 200, File: "tokiomarineam.json"},
+		"/hp/funds?FundId=999999":          {Status: 502, File: "tokiomarineam.json"},
+	}))
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dummy := "123456"
+
+	for _, c0 := range []struct {
+		fundId string
+		dummy  *string
+	}{
+		{"635333", nil},
+		{"635333", &dummy},
+	} {
+		d, err := c.Get(context.Background(), c0.fundId, c0.dummy)
+		if err != nil {
+			t.Errorf("failed to get %+v: %s", c0, err)
+			continue
+		}
+		if got, want := d.Date(), time.Date(2024, 6, 24, 18, 0, 0, 0, loc); !got.Equal(want) {
+			t.Errorf("unmatched date for %+v: want=%s got=%s", c0, want, got)
+		}
+		if got := d.ID(); got != c0.fundId {
+			t.Errorf("unmatched ID for %+v: got=%s", c0, got)
+		}
+		if got, want := d.Price(), int64(17203); got != want {
+			t.Errorf("unmatched price for %+v: want=%d got=%d", c0, want, got)
+		}
+		if got, want := d.NetAssets(), int64(34666889549); got != want {
+			t.Errorf("unmatched net assets for %+v: want=%d got=%d", c0, want, got)
+		}
+	}
+
+	for _, c0 := range []struct {
+		fundId  string
+		wantErr string
+	}{
+		{"999999", "failed HTTP with 502"},
+		{"000000", "failed HTTP with 404"},
+	} {
+		_, err := c.Get(context.Background(), c0.fundId, nil)
+		if err == nil {
+			t.Errorf("no errors for %q", c0.fundId)
+			continue
+		}
+		if !strings.Contains(err.Error(), c0.wantErr) {
+			t.Errorf("unexpected error for %q: want=%q got=%q", c0.fundId, c0.wantErr, err)
+		}
+	}
+}
+
+// TestGet accesses the production server. It runs only when FUNDDB_LIVE_TEST
+// is set.
 func TestGet(t *testing.T) {
+	if os.Getenv("FUNDDB_LIVE_TEST") == "" {
+		t.Skip("FUNDDB_LIVE_TEST is not set")
+	}
 	ctx := context.Background()
 
 	for _, c := range []struct {
@@ -1,11 +1,20 @@
 package appcore
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/config"
 	"github.com/koron/funddb/internal/dataobj"
 	"xorm.io/xorm"
 )
@@ -13,19 +22,66 @@ import (
 type Core struct {
 	ORM     *xorm.Engine
 	ShowSQL bool
+
+	// Settings are settings resolved from a config file and environment
+	// variables.
+	Settings config.Settings
+
+	dbfile string
 }
 
 type FlagHook func(fs *flag.FlagSet)
 
+// New parses flags, loads a config file and opens the database.
+// Settings of the config file and environment variables give defaults of
+// flags "dbfile", "showsql" and "format", which are overridden by flags
+// given explicitly. It fails when the database has pending migrations.
 func New(ctx context.Context, args []string, flagHooks ...FlagHook) (ac *Core, flagArgs []string, err error) {
+	ac, flagArgs, err = NewForMigration(ctx, args, flagHooks...)
+	if err != nil {
+		return nil, nil, err
+	}
+	pending, err := dataobj.PendingMigrations(ac.ORM)
+	if err != nil {
+		ac.Close()
+		return nil, nil, err
+	}
+	if len(pending) > 0 {
+		ac.Close()
+		return nil, nil, fmt.Errorf("database %s is not up to date: %d migrations are pending from #%d %q, run \"funddb database migrate\" first", ac.dbfile, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return ac, flagArgs, nil
+}
+
+// NewForMigration is New without checking migrations of the database, for
+// commands which migrate it.
+func NewForMigration(ctx context.Context, args []string, flagHooks ...FlagHook) (ac *Core, flagArgs []string, err error) {
 	name := strings.Join(subcmd.Names(ctx), " ")
 	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configFile := fs.String("config", "", "config file (default: $FUNDDB_CONFIG or funddb/config.json in the user config directory)")
+	profile := fs.String("profile", "", "profile in the config file (default: $FUNDDB_PROFILE)")
 	dbfile := fs.String("dbfile", "fund.db", "database file")
 	showsql := fs.Bool("showsql", false, "show SQL for debug")
 	for _, hook := range flagHooks {
 		hook(fs)
 	}
 	fs.Parse(args)
+
+	cfg, err := config.Load(config.Path(*configFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	settings, err := cfg.Resolve(*profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	applyDefaults(fs, map[string]string{
+		"dbfile":  settings.DBFile,
+		"showsql": strconv.FormatBool(settings.ShowSQL),
+		"format":  settings.Format,
+	})
+	settings.ApplyAdapterOptions()
+
 	orm, err := dataobj.NewEngine(*dbfile)
 	if err != nil {
 		return nil, nil, err
@@ -34,11 +90,54 @@ func New(ctx context.Context, args []string, flagHooks ...FlagHook) (ac *Core, f
 		orm.ShowSQL(true)
 	}
 	return &Core{
-		ORM:     orm,
-		ShowSQL: *showsql,
+		ORM:      orm,
+		ShowSQL:  *showsql,
+		Settings: settings,
+		dbfile:   *dbfile,
 	}, fs.Args(), nil
 }
 
+// applyDefaults sets values to flags which are defined but not given.
+// Empty values, and values which a flag rejects, are ignored. e.g. "table"
+// format is not applicable to "fund export".
+func applyDefaults(fs *flag.FlagSet, values map[string]string) {
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	for name, v := range values {
+		if v == "" || given[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		fs.Set(name, v)
+	}
+}
+
+// RetryFlags defines flags for a retry policy of fetches.
+func RetryFlags(fs *flag.FlagSet, rp *adapter.RetryPolicy) {
+	fs.IntVar(&rp.Retries, "retries", 3, "maximum number of retries for retryable errors")
+	fs.DurationVar(&rp.Wait, "retry-wait", time.Second, "base duration to wait before a retry, doubled for each retry")
+	rp.MaxWait = 5 * time.Minute
+}
+
+// Stdin is a reader of answers for Confirm.
+var Stdin = bufio.NewReader(os.Stdin)
+
+// Confirm asks a question and reads an answer from Stdin.
+func Confirm(question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	line, err := Stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 func (ac *Core) Close() error {
 	return ac.ORM.Close()
 }
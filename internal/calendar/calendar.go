@@ -0,0 +1,148 @@
+// Package calendar provides business days of Japanese funds.
+package calendar
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/koron/funddb/internal/dataobj"
+)
+
+//go:embed holidays.tsv
+var holidaysTSV []byte
+
+// YearEndClosure is a name of days which TSE closes for the year end and
+// the new year, except the new year's day.
+const YearEndClosure = "年末年始休業"
+
+// Calendar knows business days of TSE: weekdays except national holidays
+// and year-end closures. It can have extra non-business days, like foreign
+// holidays for a fund.
+type Calendar struct {
+	holidays map[dataobj.Date]string
+
+	// first and last years which the holiday table covers.
+	first, last int
+}
+
+var defaultCalendar = mustParse(holidaysTSV)
+
+// Default returns the calendar with the bundled table of holidays.
+func Default() *Calendar {
+	return defaultCalendar
+}
+
+func mustParse(b []byte) *Calendar {
+	c, err := parse(b)
+	if err != nil {
+		panic(fmt.Sprintf("invalid bundled holidays: %s", err))
+	}
+	return c
+}
+
+// parse parses a table of holidays in TSV: date and name for each line.
+func parse(b []byte) (*Calendar, error) {
+	c := &Calendar{holidays: map[dataobj.Date]string{}}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, name, _ := strings.Cut(line, "\t")
+		var d dataobj.Date
+		if err := d.Set(s); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		c.holidays[d] = name
+		if c.first == 0 || d.Year < c.first {
+			c.first = d.Year
+		}
+		c.last = max(c.last, d.Year)
+	}
+	return c, sc.Err()
+}
+
+// Covers reports whether the table of holidays covers a date. Only weekends
+// and year-end closures are known for dates out of the table.
+func (c *Calendar) Covers(d dataobj.Date) bool {
+	return d.Year >= c.first && d.Year <= c.last
+}
+
+// WithHolidays returns a copy of the calendar with extra non-business days.
+func (c *Calendar) WithHolidays(extra map[dataobj.Date]string) *Calendar {
+	holidays := maps.Clone(c.holidays)
+	maps.Copy(holidays, extra)
+	return &Calendar{holidays: holidays, first: c.first, last: c.last}
+}
+
+// Holiday returns a name of a non-business day, and false for business days.
+// Weekends are named by their weekdays.
+func (c *Calendar) Holiday(d dataobj.Date) (string, bool) {
+	if name, ok := c.holidays[d]; ok {
+		return name, true
+	}
+	if (d.Month == 12 && d.Day == 31) || (d.Month == 1 && d.Day <= 3) {
+		return YearEndClosure, true
+	}
+	switch wd := d.Time(time.UTC).Weekday(); wd {
+	case time.Saturday, time.Sunday:
+		return wd.String(), true
+	}
+	return "", false
+}
+
+// IsBusinessDay reports whether a date is a business day.
+func (c *Calendar) IsBusinessDay(d dataobj.Date) bool {
+	_, ok := c.Holiday(d)
+	return !ok
+}
+
+// LastBusinessDay returns the latest business day on or before d.
+func (c *Calendar) LastBusinessDay(d dataobj.Date) dataobj.Date {
+	for !c.IsBusinessDay(d) {
+		d = d.AddDays(-1)
+	}
+	return d
+}
+
+// BusinessDays returns business days between from and to, both inclusive.
+func (c *Calendar) BusinessDays(from, to dataobj.Date) []dataobj.Date {
+	var days []dataobj.Date
+	for d := from; d.Compare(to) <= 0; d = d.AddDays(1) {
+		if c.IsBusinessDay(d) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// Gaps returns business days between from and to, both inclusive, which
+// are not in dates.
+func (c *Calendar) Gaps(dates []dataobj.Date, from, to dataobj.Date) []dataobj.Date {
+	have := make(map[dataobj.Date]struct{}, len(dates))
+	for _, d := range dates {
+		have[d] = struct{}{}
+	}
+	var gaps []dataobj.Date
+	for _, d := range c.BusinessDays(from, to) {
+		if _, ok := have[d]; !ok {
+			gaps = append(gaps, d)
+		}
+	}
+	return gaps
+}
+
+// Age returns the number of business days after d until ref, inclusive.
+// It is zero when d is ref or later.
+func (c *Calendar) Age(d, ref dataobj.Date) int {
+	if d.Compare(ref) >= 0 {
+		return 0
+	}
+	return len(c.BusinessDays(d.AddDays(1), ref))
+}
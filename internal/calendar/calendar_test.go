@@ -0,0 +1,107 @@
+package calendar_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/koron/funddb/internal/calendar"
+	"github.com/koron/funddb/internal/dataobj"
+)
+
+func date(s string) dataobj.Date {
+	var d dataobj.Date
+	if err := d.Set(s); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestHoliday(t *testing.T) {
+	c := calendar.Default()
+	for _, tc := range []struct {
+		date string
+		name string
+		ok   bool
+	}{
+		{"2024-01-01", "元日", true},
+		{"2024-01-02", calendar.YearEndClosure, true},
+		{"2024-01-04", "", false},
+		{"2024-02-12", "振替休日", true},
+		{"2024-03-20", "春分の日", true},
+		{"2024-05-06", "振替休日", true},
+		{"2024-06-15", "Saturday", true},
+		{"2024-06-16", "Sunday", true},
+		{"2024-06-17", "", false},
+		{"2024-12-31", calendar.YearEndClosure, true},
+		{"2026-09-22", "国民の休日", true},
+		{"2035-01-03", calendar.YearEndClosure, true},
+	} {
+		name, ok := c.Holiday(date(tc.date))
+		if name != tc.name || ok != tc.ok {
+			t.Errorf("unmatch holiday for %s: want=%q,%t got=%q,%t", tc.date, tc.name, tc.ok, name, ok)
+		}
+	}
+	if !c.Covers(date("2024-01-01")) || c.Covers(date("2035-01-01")) {
+		t.Errorf("unexpected coverage of the table")
+	}
+}
+
+func TestWithHolidays(t *testing.T) {
+	c := calendar.Default().WithHolidays(map[dataobj.Date]string{
+		date("2024-07-04"): "Independence Day",
+	})
+	if c.IsBusinessDay(date("2024-07-04")) {
+		t.Errorf("extra holiday should not be a business day")
+	}
+	if !calendar.Default().IsBusinessDay(date("2024-07-04")) {
+		t.Errorf("default calendar should not be modified")
+	}
+}
+
+func TestGaps(t *testing.T) {
+	c := calendar.Default()
+	dates := []dataobj.Date{
+		date("2024-04-26"), date("2024-04-30"), date("2024-05-02"), date("2024-05-07"),
+	}
+	got := c.Gaps(dates, date("2024-04-26"), date("2024-05-08"))
+	want := []dataobj.Date{date("2024-05-01"), date("2024-05-08")}
+	if !slices.Equal(got, want) {
+		t.Errorf("unmatch gaps: want=%v got=%v", want, got)
+	}
+}
+
+func TestAge(t *testing.T) {
+	c := calendar.Default()
+	for _, tc := range []struct {
+		date string
+		ref  string
+		want int
+	}{
+		{"2024-05-02", "2024-05-02", 0},
+		{"2024-05-02", "2024-05-01", 0},
+		{"2024-05-02", "2024-05-06", 0},
+		{"2024-05-02", "2024-05-07", 1},
+		{"2024-04-26", "2024-05-07", 4},
+	} {
+		if got := c.Age(date(tc.date), date(tc.ref)); got != tc.want {
+			t.Errorf("unmatch age of %s at %s: want=%d got=%d", tc.date, tc.ref, tc.want, got)
+		}
+	}
+}
+
+func TestLastBusinessDay(t *testing.T) {
+	c := calendar.Default()
+	for _, tc := range []struct {
+		date string
+		want string
+	}{
+		{"2024-06-17", "2024-06-17"},
+		{"2024-06-16", "2024-06-14"},
+		{"2024-05-06", "2024-05-02"},
+		{"2025-01-05", "2024-12-30"},
+	} {
+		if got := c.LastBusinessDay(date(tc.date)); got != date(tc.want) {
+			t.Errorf("unmatch last business day of %s: want=%s got=%s", tc.date, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Package config loads settings from a config file and environment
+// variables.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/adapter/command"
+	"github.com/koron/funddb/internal/adapter/generic"
+)
+
+// Duration is a time.Duration which is written as "30s" in JSON.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// HTTP is settings for HTTP requests of adapters.
+type HTTP struct {
+	// BaseURL overrides the production URL of a provider. It is meaningful
+	// only for an adapter.
+	BaseURL   string   `json:"base_url,omitempty"`
+	UserAgent string   `json:"user_agent,omitempty"`
+	Timeout   Duration `json:"timeout,omitempty"`
+}
+
+// merge overwrites fields of h with non-zero fields of o.
+func (h HTTP) merge(o HTTP) HTTP {
+	if o.BaseURL != "" {
+		h.BaseURL = o.BaseURL
+	}
+	if o.UserAgent != "" {
+		h.UserAgent = o.UserAgent
+	}
+	if o.Timeout != 0 {
+		h.Timeout = o.Timeout
+	}
+	return h
+}
+
+// Options converts to adapter.Options.
+func (h HTTP) Options() adapter.Options {
+	return adapter.Options{
+		BaseURL:   h.BaseURL,
+		UserAgent: h.UserAgent,
+		Timeout:   time.Duration(h.Timeout),
+	}
+}
+
+// Command is a command for the "exec" scheme.
+type Command struct {
+	// Path is a path of an executable. A path with separators is resolved
+	// from the directory of the config file, and others are searched in
+	// PATH.
+	Path    string   `json:"path"`
+	Args    []string `json:"args,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// Command converts to command.Command.
+func (c Command) Command() command.Command {
+	return command.Command{
+		Path:    c.Path,
+		Args:    c.Args,
+		Env:     c.Env,
+		Timeout: time.Duration(c.Timeout),
+	}
+}
+
+// Settings is a set of settings, which is given at the top level or by a
+// profile of a config file.
+type Settings struct {
+	// DBFile is a path of the database file. A relative path is resolved
+	// from the directory of the config file.
+	DBFile  string `json:"dbfile,omitempty"`
+	ShowSQL bool   `json:"showsql,omitempty"`
+
+	// Format is the default output format of commands.
+	Format string `json:"format,omitempty"`
+
+	// HTTP is settings for all adapters.
+	HTTP HTTP `json:"http"`
+
+	// Adapters are settings for each scheme, which override HTTP.
+	Adapters map[string]HTTP `json:"adapters,omitempty"`
+
+	// Providers are declarations of providers for the "generic" scheme.
+	Providers map[string]generic.Provider `json:"providers,omitempty"`
+
+	// Commands are commands for the "exec" scheme.
+	Commands map[string]Command `json:"commands,omitempty"`
+}
+
+// merge overwrites fields of s with non-zero fields of o.
+func (s Settings) merge(o Settings) Settings {
+	if o.DBFile != "" {
+		s.DBFile = o.DBFile
+	}
+	if o.ShowSQL {
+		s.ShowSQL = true
+	}
+	if o.Format != "" {
+		s.Format = o.Format
+	}
+	s.HTTP = s.HTTP.merge(o.HTTP)
+	if len(o.Adapters) > 0 {
+		adapters := make(map[string]HTTP, len(s.Adapters)+len(o.Adapters))
+		for k, v := range s.Adapters {
+			adapters[k] = v
+		}
+		for k, v := range o.Adapters {
+			adapters[k] = adapters[k].merge(v)
+		}
+		s.Adapters = adapters
+	}
+	if len(o.Providers) > 0 {
+		providers := make(map[string]generic.Provider, len(s.Providers)+len(o.Providers))
+		for k, v := range s.Providers {
+			providers[k] = v
+		}
+		for k, v := range o.Providers {
+			providers[k] = v
+		}
+		s.Providers = providers
+	}
+	if len(o.Commands) > 0 {
+		commands := make(map[string]Command, len(s.Commands)+len(o.Commands))
+		for k, v := range s.Commands {
+			commands[k] = v
+		}
+		for k, v := range o.Commands {
+			commands[k] = v
+		}
+		s.Commands = commands
+	}
+	return s
+}
+
+// resolvePath resolves a path of a DB file, relative to dir.
+func resolvePath(dir, p string) string {
+	if p == "" {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(p) || dir == "" {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// resolveCommands resolves paths of commands which have separators,
+// relative to dir.
+func resolveCommands(dir string, commands map[string]Command) {
+	for name, c := range commands {
+		if strings.ContainsRune(c.Path, '/') || strings.ContainsRune(c.Path, filepath.Separator) {
+			c.Path = resolvePath(dir, c.Path)
+			commands[name] = c
+		}
+	}
+}
+
+// Config is a content of a config file.
+type Config struct {
+	Settings
+
+	// Profile is a name of the profile used when no profiles are specified.
+	Profile string `json:"profile,omitempty"`
+
+	// Profiles are named settings, which override the top level settings.
+	Profiles map[string]Settings `json:"profiles,omitempty"`
+}
+
+// Environment variables to specify a config file and a profile.
+const (
+	EnvConfig  = "FUNDDB_CONFIG"
+	EnvProfile = "FUNDDB_PROFILE"
+)
+
+// Path returns a path of the config file. It uses name when not empty,
+// $FUNDDB_CONFIG, or "funddb/config.json" in the user config directory
+// ($XDG_CONFIG_HOME or ~/.config on Linux) in order. explicit is false for
+// the last one, which is allowed to be absent.
+func Path(name string) (path string, explicit bool) {
+	if name != "" {
+		return name, true
+	}
+	if s := os.Getenv(EnvConfig); s != "" {
+		return s, true
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, "funddb", "config.json"), false
+}
+
+// Load loads a config file. It returns an empty config, when the file is
+// absent and not explicit.
+func Load(path string, explicit bool) (*Config, error) {
+	if path == "" {
+		return &Config{}, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	c.DBFile = resolvePath(dir, c.DBFile)
+	resolveCommands(dir, c.Commands)
+	for name, p := range c.Profiles {
+		p.DBFile = resolvePath(dir, p.DBFile)
+		resolveCommands(dir, p.Commands)
+		c.Profiles[name] = p
+	}
+	return &c, nil
+}
+
+// Resolve composes settings for a profile. The profile falls back to
+// $FUNDDB_PROFILE and Profile of the config. Environment variables override
+// settings of the config file, see ApplyEnv.
+func (c *Config) Resolve(profile string) (Settings, error) {
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile == "" {
+		profile = c.Profile
+	}
+	s := c.Settings
+	if profile != "" {
+		p, ok := c.Profiles[profile]
+		if !ok {
+			return Settings{}, fmt.Errorf("unknown profile %q", profile)
+		}
+		s = s.merge(p)
+	}
+	for name, p := range s.Providers {
+		if err := p.Validate(); err != nil {
+			return Settings{}, fmt.Errorf("invalid provider %q: %w", name, err)
+		}
+		// keep compiled patterns.
+		s.Providers[name] = p
+	}
+	for name, c := range s.Commands {
+		if c.Path == "" {
+			return Settings{}, fmt.Errorf("invalid command %q: path is required", name)
+		}
+	}
+	return s.ApplyEnv(os.Getenv)
+}
+
+// ApplyEnv overrides settings with environment variables: FUNDDB_DBFILE,
+// FUNDDB_SHOWSQL, FUNDDB_FORMAT, FUNDDB_USER_AGENT and FUNDDB_HTTP_TIMEOUT.
+func (s Settings) ApplyEnv(getenv func(string) string) (Settings, error) {
+	if v := getenv("FUNDDB_DBFILE"); v != "" {
+		s.DBFile = v
+	}
+	if v := getenv("FUNDDB_SHOWSQL"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Settings{}, fmt.Errorf("invalid FUNDDB_SHOWSQL: %w", err)
+		}
+		s.ShowSQL = b
+	}
+	if v := getenv("FUNDDB_FORMAT"); v != "" {
+		s.Format = v
+	}
+	if v := getenv("FUNDDB_USER_AGENT"); v != "" {
+		s.HTTP.UserAgent = v
+	}
+	if v := getenv("FUNDDB_HTTP_TIMEOUT"); v != "" {
+		if err := s.HTTP.Timeout.UnmarshalText([]byte(v)); err != nil {
+			return Settings{}, fmt.Errorf("invalid FUNDDB_HTTP_TIMEOUT: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// ApplyAdapterOptions sets HTTP settings, declared providers and commands
+// to adapters.
+func (s Settings) ApplyAdapterOptions() {
+	adapter.SetDefaultOptions(s.HTTP.Options())
+	for scheme, h := range s.Adapters {
+		adapter.SetOptions(scheme, h.Options())
+	}
+	generic.SetProviders(s.Providers)
+	commands := make(map[string]command.Command, len(s.Commands))
+	for name, c := range s.Commands {
+		commands[name] = c.Command()
+	}
+	command.SetCommands(commands)
+}
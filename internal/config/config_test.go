@@ -0,0 +1,171 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/config"
+)
+
+func writeConfig(t *testing.T, s string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(name, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+const testConfig = `{
+	"dbfile": "fund.db",
+	"format": "csv",
+	"http": {"user_agent": "funddb-test", "timeout": "10s"},
+	"adapters": {"ammufg": {"base_url": "http://127.0.0.1:8080"}},
+	"profile": "home",
+	"profiles": {
+		"home": {"dbfile": "/var/lib/funddb/home.db"},
+		"work": {"dbfile": "work.db", "http": {"timeout": "1m"}, "adapters": {"ammufg": {"timeout": "5s"}}}
+	}
+}`
+
+func TestResolve(t *testing.T) {
+	name := writeConfig(t, testConfig)
+	dir := filepath.Dir(name)
+	c, err := config.Load(name, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvProfile, "")
+
+	s, err := c.Resolve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DBFile != "/var/lib/funddb/home.db" || s.Format != "csv" || s.HTTP.UserAgent != "funddb-test" || time.Duration(s.HTTP.Timeout) != 10*time.Second {
+		t.Errorf("unexpected default profile: %+v", s)
+	}
+
+	s, err = c.Resolve("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "work.db"); s.DBFile != want {
+		t.Errorf("unmatch dbfile: want=%s got=%s", want, s.DBFile)
+	}
+	if time.Duration(s.HTTP.Timeout) != time.Minute || s.HTTP.UserAgent != "funddb-test" {
+		t.Errorf("unexpected HTTP settings: %+v", s.HTTP)
+	}
+	if a := s.Adapters["ammufg"]; a.BaseURL != "http://127.0.0.1:8080" || time.Duration(a.Timeout) != 5*time.Second {
+		t.Errorf("unexpected adapter settings: %+v", a)
+	}
+
+	if _, err := c.Resolve("unknown"); err == nil {
+		t.Errorf("unknown profile should fail")
+	}
+
+	t.Setenv(config.EnvProfile, "work")
+	t.Setenv("FUNDDB_DBFILE", "env.db")
+	t.Setenv("FUNDDB_HTTP_TIMEOUT", "3s")
+	s, err = c.Resolve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DBFile != "env.db" || time.Duration(s.HTTP.Timeout) != 3*time.Second {
+		t.Errorf("environment variables should override: %+v", s)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := config.Load(missing, true); err == nil {
+		t.Errorf("missing explicit config should fail")
+	}
+	c, err := config.Load(missing, false)
+	if err != nil {
+		t.Fatalf("missing implicit config should be ignored: %s", err)
+	}
+	if c.DBFile != "" || len(c.Profiles) != 0 {
+		t.Errorf("unexpected config: %+v", c)
+	}
+	if _, err := config.Load(writeConfig(t, `{"http": {"timeout": "soon"}}`), true); err == nil {
+		t.Errorf("invalid duration should fail")
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv(config.EnvConfig, "/etc/funddb.json")
+	if p, explicit := config.Path("my.json"); p != "my.json" || !explicit {
+		t.Errorf("flag should be used: %s %t", p, explicit)
+	}
+	if p, explicit := config.Path(""); p != "/etc/funddb.json" || !explicit {
+		t.Errorf("environment variable should be used: %s %t", p, explicit)
+	}
+	t.Setenv(config.EnvConfig, "")
+	t.Setenv("XDG_CONFIG_HOME", "/home/test/.config")
+	if p, explicit := config.Path(""); p != "/home/test/.config/funddb/config.json" || explicit {
+		t.Errorf("user config directory should be used: %s %t", p, explicit)
+	}
+}
+
+func TestProviders(t *testing.T) {
+	c, err := config.Load(writeConfig(t, `{
+	"providers": {
+		"example": {"url": "https://example.com/{id}.json", "type": "json", "date": {"path": "nav.date"}, "price": {"path": "nav.price"}}
+	},
+	"profiles": {
+		"html": {"providers": {"other": {"url": "https://example.net/{id}", "type": "html", "date": {"selector": ".date"}, "price": {"selector": ".price", "unit": "円"}}}},
+		"bad": {"providers": {"example": {"url": "https://example.com/{id}", "type": "html", "date": {"path": "nav.date"}, "price": {"path": "nav.price"}}}}
+	}
+}`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvProfile, "")
+	s, err := c.Resolve("html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Providers["example"]; !ok {
+		t.Errorf("providers of the top level should be kept: %+v", s.Providers)
+	}
+	if p := s.Providers["other"]; p.Price.Unit != "円" {
+		t.Errorf("unexpected provider: %+v", p)
+	}
+	if _, err := c.Resolve("bad"); err == nil || !strings.Contains(err.Error(), `invalid provider "example"`) {
+		t.Errorf("unexpected error for invalid provider: %v", err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	name := writeConfig(t, `{
+	"commands": {
+		"inhouse": {"path": "bin/fetch-price", "args": ["-q"], "timeout": "1m"},
+		"python": {"path": "python3", "args": ["fetch.py"]}
+	},
+	"profiles": {
+		"bad": {"commands": {"inhouse": {"args": ["-v"]}}}
+	}
+}`)
+	c, err := config.Load(name, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvProfile, "")
+	s, err := c.Resolve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := s.Commands["inhouse"].Command()
+	if want := filepath.Join(filepath.Dir(name), "bin/fetch-price"); cmd.Path != want || cmd.Timeout != time.Minute || len(cmd.Args) != 1 {
+		t.Errorf("unexpected command: %+v", cmd)
+	}
+	if p := s.Commands["python"].Path; p != "python3" {
+		t.Errorf("a command without separators should be kept: %s", p)
+	}
+	if _, err := c.Resolve("bad"); err == nil || !strings.Contains(err.Error(), `invalid command "inhouse"`) {
+		t.Errorf("unexpected error for invalid command: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package dataobj
 
 import (
+	"cmp"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
@@ -26,6 +27,52 @@ func (d Date) String() string {
 	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
 }
 
+// IsZero reports whether d is the zero value.
+func (d Date) IsZero() bool {
+	return d.Year == 0 && d.Month == 0 && d.Day == 0
+}
+
+// Time returns the time at the beginning of the date in a location.
+func (d Date) Time(loc *time.Location) time.Time {
+	return time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, loc)
+}
+
+// Compare compares d with e, and returns -1 if d is before e, +1 if d is
+// after e, or 0 if they are same.
+func (d Date) Compare(e Date) int {
+	switch {
+	case d.Year != e.Year:
+		return cmp.Compare(d.Year, e.Year)
+	case d.Month != e.Month:
+		return cmp.Compare(d.Month, e.Month)
+	default:
+		return cmp.Compare(d.Day, e.Day)
+	}
+}
+
+// AddDays returns the date n days after d.
+func (d Date) AddDays(n int) Date {
+	return DateFromTime(d.Time(time.UTC).AddDate(0, 0, n))
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(b []byte) error {
+	ti, err := time.Parse(time.DateOnly, string(b))
+	if err != nil {
+		return err
+	}
+	*d = DateFromTime(ti)
+	return nil
+}
+
+// Set parses a date in "YYYY-MM-DD" format, to implement flag.Value.
+func (d *Date) Set(s string) error {
+	return d.UnmarshalText([]byte(s))
+}
+
 var _ driver.Valuer = Date{}
 
 func (d Date) Value() (driver.Value, error) {
@@ -0,0 +1,359 @@
+package dataobj
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/koron/funddb/internal/xormhelper"
+	"xorm.io/xorm"
+)
+
+// Migration is a numbered change of schema.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(*xorm.Session) error
+	Down    func(*xorm.Session) error
+}
+
+// execAll returns a function which executes statements in order.
+func execAll(stmts ...string) func(*xorm.Session) error {
+	return func(session *xorm.Session) error {
+		for i, s := range stmts {
+			if _, err := session.Exec(s); err != nil {
+				return fmt.Errorf("statement #%d failed: %w", i, err)
+			}
+		}
+		return nil
+	}
+}
+
+// hasColumn checks a table has a column.
+func hasColumn(session *xorm.Session, table, column string) (bool, error) {
+	var n int
+	_, err := session.SQL(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Get(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// addColumn returns a function which adds a column to a table, when the
+// column doesn't exist. It allows to upgrade databases which have been
+// created by old "initschema" or "syncorm".
+func addColumn(table, column, def string) func(*xorm.Session) error {
+	return func(session *xorm.Session) error {
+		has, err := hasColumn(session, table, column)
+		if err != nil || has {
+			return err
+		}
+		_, err = session.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
+		return err
+	}
+}
+
+// dropColumn returns a function which drops a column from a table.
+func dropColumn(table, column string) func(*xorm.Session) error {
+	return execAll(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column))
+}
+
+// Migrations is the list of all migrations in order of versions. Steps of
+// migrations should be idempotent, so they can be applied to databases which
+// were created before migrations were introduced.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create funds",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS funds (
+				id       TEXT PRIMARY KEY NOT NULL,
+				name     TEXT NOT NULL,
+				url      TEXT NOT NULL,
+				fetch_id TEXT NULL)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS UQE_funds_name ON funds (name)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS UQE_funds_url ON funds (url)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS UQE_funds_fetch_id ON funds (fetch_id)`,
+		),
+		Down: execAll(`DROP TABLE IF EXISTS funds`),
+	},
+	{
+		Version: 2,
+		Name:    "create prices",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS prices (
+				id    TEXT    NOT NULL,
+				date  TEXT    NOT NULL,
+				value INTEGER NOT NULL,
+				PRIMARY KEY (id, date),
+				FOREIGN KEY (id) REFERENCES funds (id) ON DELETE CASCADE)`,
+			`CREATE INDEX IF NOT EXISTS IDX_prices_id ON prices (id)`,
+			`CREATE INDEX IF NOT EXISTS IDX_prices_date ON prices (date)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS UQE_prices_id_date ON prices (id, date)`,
+		),
+		Down: execAll(`DROP TABLE IF EXISTS prices`),
+	},
+	{
+		Version: 3,
+		Name:    "add net_assets to prices",
+		Up:      addColumn("prices", "net_assets", "INTEGER NULL"),
+		Down:    dropColumn("prices", "net_assets"),
+	},
+	{
+		Version: 4,
+		Name:    "create fetch_runs and fetch_results",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS fetch_runs (
+				id          INTEGER  PRIMARY KEY AUTOINCREMENT,
+				started_at  DATETIME NOT NULL,
+				finished_at DATETIME NULL,
+				total       INTEGER  NOT NULL,
+				failed      INTEGER  NOT NULL)`,
+			`CREATE TABLE IF NOT EXISTS fetch_results (
+				run_id       INTEGER NOT NULL,
+				fund_id      TEXT    NOT NULL,
+				fetch_id     TEXT    NOT NULL,
+				status       TEXT    NOT NULL,
+				error        TEXT    NULL,
+				latency_msec INTEGER NULL,
+				date         TEXT    NULL,
+				price        INTEGER NULL,
+				PRIMARY KEY (run_id, fund_id),
+				FOREIGN KEY (run_id) REFERENCES fetch_runs (id) ON DELETE CASCADE,
+				FOREIGN KEY (fund_id) REFERENCES funds (id) ON DELETE CASCADE)`,
+			`CREATE INDEX IF NOT EXISTS IDX_fetch_results_fund_id ON fetch_results (fund_id)`,
+		),
+		Down: execAll(
+			`DROP TABLE IF EXISTS fetch_results`,
+			`DROP TABLE IF EXISTS fetch_runs`,
+		),
+	},
+	{
+		Version: 5,
+		Name:    "create distributions",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS distributions (
+				id      TEXT    NOT NULL,
+				ex_date TEXT    NOT NULL,
+				amount  INTEGER NOT NULL,
+				PRIMARY KEY (id, ex_date),
+				FOREIGN KEY (id) REFERENCES funds (id) ON DELETE CASCADE)`,
+			`CREATE INDEX IF NOT EXISTS IDX_distributions_id ON distributions (id)`,
+		),
+		Down: execAll(`DROP TABLE IF EXISTS distributions`),
+	},
+	{
+		Version: 6,
+		Name:    "create accounts and transactions",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS accounts (
+				id   TEXT PRIMARY KEY NOT NULL,
+				name TEXT NOT NULL)`,
+			`CREATE TABLE IF NOT EXISTS transactions (
+				id         INTEGER PRIMARY KEY AUTOINCREMENT,
+				account_id TEXT    NOT NULL,
+				fund_id    TEXT    NOT NULL,
+				date       TEXT    NOT NULL,
+				type       TEXT    NOT NULL CHECK (type IN ('buy', 'sell', 'reinvest', 'fee')),
+				units      INTEGER NOT NULL,
+				amount     INTEGER NOT NULL,
+				note       TEXT    NULL,
+				FOREIGN KEY (account_id) REFERENCES accounts (id) ON DELETE CASCADE,
+				FOREIGN KEY (fund_id) REFERENCES funds (id) ON DELETE CASCADE)`,
+			`CREATE INDEX IF NOT EXISTS IDX_transactions_account_id ON transactions (account_id)`,
+			`CREATE INDEX IF NOT EXISTS IDX_transactions_fund_id ON transactions (fund_id)`,
+		),
+		Down: execAll(
+			`DROP TABLE IF EXISTS transactions`,
+			`DROP TABLE IF EXISTS accounts`,
+		),
+	},
+	{
+		Version: 7,
+		Name:    "create fund_holidays",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS fund_holidays (
+				fund_id TEXT NOT NULL,
+				date    TEXT NOT NULL,
+				note    TEXT NULL,
+				PRIMARY KEY (fund_id, date),
+				FOREIGN KEY (fund_id) REFERENCES funds (id) ON DELETE CASCADE)`,
+			`CREATE INDEX IF NOT EXISTS IDX_fund_holidays_fund_id ON fund_holidays (fund_id)`,
+		),
+		Down: execAll(`DROP TABLE IF EXISTS fund_holidays`),
+	},
+	{
+		Version: 8,
+		Name:    "create fund_metrics",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS fund_metrics (
+				fund_id  TEXT NOT NULL,
+				date     TEXT NOT NULL,
+				name     TEXT NOT NULL,
+				value    TEXT NOT NULL,
+				ref_date TEXT NULL,
+				PRIMARY KEY (fund_id, date, name),
+				FOREIGN KEY (fund_id) REFERENCES funds (id) ON DELETE CASCADE)`,
+			`CREATE INDEX IF NOT EXISTS IDX_fund_metrics_fund_id ON fund_metrics (fund_id)`,
+		),
+		Down: execAll(`DROP TABLE IF EXISTS fund_metrics`),
+	},
+}
+
+// SchemaMigration is a record of an applied migration.
+type SchemaMigration struct {
+	Version   int       `xorm:"pk"`
+	Name      string    `xorm:"notnull"`
+	AppliedAt time.Time `xorm:"notnull"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER  PRIMARY KEY NOT NULL,
+	name       TEXT     NOT NULL,
+	applied_at DATETIME NOT NULL)`
+
+// MigrationStatus is a migration with its applied status.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+func appliedMigrations(engine *xorm.Engine) (map[int]SchemaMigration, error) {
+	if _, err := engine.Exec(createSchemaMigrations); err != nil {
+		return nil, err
+	}
+	var list []SchemaMigration
+	if err := engine.Find(&list); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(list))
+	for _, m := range list {
+		applied[m.Version] = m
+	}
+	return applied, nil
+}
+
+// MigrationStatuses returns statuses of all migrations.
+func MigrationStatuses(engine *xorm.Engine) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(engine)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]MigrationStatus, len(Migrations))
+	for i, m := range Migrations {
+		sm, ok := applied[m.Version]
+		list[i] = MigrationStatus{Migration: m, Applied: ok, AppliedAt: sm.AppliedAt}
+	}
+	return list, nil
+}
+
+// Migrate applies all pending migrations in order. Each migration is applied
+// in its own transaction.
+func Migrate(engine *xorm.Engine, verbose bool) error {
+	if verbose {
+		engine.ShowSQL(true)
+		defer engine.ShowSQL(false)
+	}
+	applied, err := appliedMigrations(engine)
+	if err != nil {
+		return err
+	}
+	for _, m := range Migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := xormhelper.Tx(engine, func(session *xorm.Session) error {
+			if err := m.Up(session); err != nil {
+				return err
+			}
+			_, err := session.Insert(&SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration #%d %q failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigrateDown reverts applied migrations which have greater versions than
+// "to", in reverse order.
+func MigrateDown(engine *xorm.Engine, to int, verbose bool) error {
+	if verbose {
+		engine.ShowSQL(true)
+		defer engine.ShowSQL(false)
+	}
+	applied, err := appliedMigrations(engine)
+	if err != nil {
+		return err
+	}
+	for i := len(Migrations) - 1; i >= 0; i-- {
+		m := Migrations[i]
+		if m.Version <= to {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		err := xormhelper.Tx(engine, func(session *xorm.Session) error {
+			if err := m.Down(session); err != nil {
+				return err
+			}
+			_, err := session.Where("version = ?", m.Version).Delete(&SchemaMigration{})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("reverting migration #%d %q failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// PendingMigrations returns migrations which are not applied yet. Unlike
+// others, it doesn't create the table of applied migrations, to keep
+// databases untouched.
+func PendingMigrations(engine *xorm.Engine) ([]Migration, error) {
+	ok, err := engine.IsTableExist(&SchemaMigration{})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return Migrations, nil
+	}
+	var list []SchemaMigration
+	if err := engine.Find(&list); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]bool, len(list))
+	for _, m := range list {
+		applied[m.Version] = true
+	}
+	var pending []Migration
+	for _, m := range Migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// LatestVersion returns the version of the latest applied migration, or zero.
+func LatestVersion(engine *xorm.Engine) (int, error) {
+	applied, err := appliedMigrations(engine)
+	if err != nil {
+		return 0, err
+	}
+	var v int
+	for k := range applied {
+		v = max(v, k)
+	}
+	return v, nil
+}
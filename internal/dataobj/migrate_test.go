@@ -0,0 +1,128 @@
+package dataobj_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/koron/funddb/internal/dataobj"
+	"xorm.io/xorm"
+)
+
+func newTestEngine(t *testing.T) *xorm.Engine {
+	t.Helper()
+	engine, err := dataobj.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func countApplied(t *testing.T, engine *xorm.Engine) int {
+	t.Helper()
+	list, err := dataobj.MigrationStatuses(engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for _, m := range list {
+		if m.Applied {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMigrate(t *testing.T) {
+	engine := newTestEngine(t)
+	all := len(dataobj.Migrations)
+
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := countApplied(t, engine); n != all {
+		t.Fatalf("unmatch applied migrations: want=%d got=%d", all, n)
+	}
+	// migrate again should do nothing.
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dataobj.MigrateDown(engine, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := countApplied(t, engine); n != 0 {
+		t.Fatalf("some migrations are left: %d", n)
+	}
+	tables, err := engine.DBMetas()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tbl := range tables {
+		if tbl.Name != "schema_migrations" && tbl.Name != "sqlite_sequence" {
+			t.Errorf("table %s is left", tbl.Name)
+		}
+	}
+
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := countApplied(t, engine); n != all {
+		t.Fatalf("unmatch applied migrations: want=%d got=%d", all, n)
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	engine := newTestEngine(t)
+	pending, err := dataobj.PendingMigrations(engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(dataobj.Migrations) {
+		t.Errorf("all migrations should be pending: %d", len(pending))
+	}
+	if ok, err := engine.IsTableExist(&dataobj.SchemaMigration{}); err != nil || ok {
+		t.Errorf("schema_migrations should not be created: ok=%t err=%v", ok, err)
+	}
+
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+	last := dataobj.Migrations[len(dataobj.Migrations)-1]
+	if err := dataobj.MigrateDown(engine, last.Version-1, false); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = dataobj.PendingMigrations(engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Version != last.Version {
+		t.Errorf("only the last migration should be pending: %+v", pending)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	engine := newTestEngine(t)
+	// schema which is created by old "initschema" without net_assets.
+	for _, s := range []string{
+		`CREATE TABLE funds (id TEXT PRIMARY KEY NOT NULL, name TEXT NOT NULL, url TEXT NOT NULL, fetch_id TEXT NULL)`,
+		`CREATE TABLE prices (id TEXT NOT NULL, date TEXT NOT NULL, value INTEGER NOT NULL, PRIMARY KEY (id, date))`,
+		`INSERT INTO funds VALUES ('A', 'Fund A', 'https://example.com/a', NULL)`,
+		`INSERT INTO prices VALUES ('A', '2024-06-24', 12345)`,
+	} {
+		if _, err := engine.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+	var p dataobj.Price
+	has, err := engine.Where("id = ?", "A").Get(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !has || p.Value != 12345 || p.Date != dataobj.NewDate(2024, 6, 24) {
+		t.Errorf("unexpected price: has=%t price=%+v", has, p)
+	}
+}
@@ -0,0 +1,46 @@
+package dataobj
+
+import (
+	"slices"
+
+	"xorm.io/xorm"
+)
+
+// PriceQuery is a condition to query prices of a fund.
+type PriceQuery struct {
+	From Date // zero means no lower limit
+	To   Date // zero means no upper limit
+	Last int  // limit to last N prices when positive
+}
+
+// Find queries prices of a fund in ascending order of dates. It returns the
+// price just before the first one too, to calculate changes.
+func (q PriceQuery) Find(orm *xorm.Engine, id string) (prices []Price, prev *Price, err error) {
+	session := orm.Where("id = ?", id)
+	if !q.From.IsZero() {
+		session.And("date >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		session.And("date <= ?", q.To)
+	}
+	session.Desc("date")
+	if q.Last > 0 {
+		session.Limit(q.Last)
+	}
+	if err := session.Find(&prices); err != nil {
+		return nil, nil, err
+	}
+	slices.Reverse(prices)
+	if len(prices) == 0 {
+		return prices, nil, nil
+	}
+	var p Price
+	has, err := orm.Where("id = ? AND date < ?", id, prices[0].Date).Desc("date").Get(&p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if has {
+		prev = &p
+	}
+	return prices, prev, nil
+}
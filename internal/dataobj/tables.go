@@ -1,5 +1,11 @@
 package dataobj
 
+import (
+	"time"
+
+	"github.com/koron/funddb/internal/decimal"
+)
+
 type Fund struct {
 	ID      string `xorm:"pk"`             // Association ID
 	Name    string `xorm:"notnull unique"` // Display name
@@ -23,4 +29,113 @@ func (Price) TableName() string {
 	return "prices"
 }
 
-var Beans = []any{&Fund{}, &Price{}}
+// FetchRun is a run of fetching latest prices.
+type FetchRun struct {
+	ID         int64     `xorm:"pk autoincr"`
+	StartedAt  time.Time `xorm:"notnull"`
+	FinishedAt time.Time `xorm:"null"`
+	Total      int       `xorm:"notnull"` // Number of fetched funds
+	Failed     int       `xorm:"notnull"` // Number of failed funds
+}
+
+func (FetchRun) TableName() string {
+	return "fetch_runs"
+}
+
+// Status values of FetchResult.
+const (
+	FetchStatusOK    = "ok"
+	FetchStatusError = "error"
+)
+
+// FetchResult is an outcome of fetching a fund in a FetchRun.
+type FetchResult struct {
+	RunID       int64  `xorm:"notnull index pk"` // FK:FetchRun.ID
+	FundID      string `xorm:"notnull index pk"` // FK:Fund.ID
+	FetchID     string `xorm:"notnull"`
+	Status      string `xorm:"notnull"`
+	Error       string `xorm:"null"`
+	LatencyMsec int64  `xorm:"null"`
+	Date        Date   `xorm:"null"` // Date of the fetched price
+	Price       int64  `xorm:"bigint null"`
+}
+
+func (FetchResult) TableName() string {
+	return "fetch_results"
+}
+
+// Distribution is a distribution (dividend) of a fund.
+type Distribution struct {
+	ID     string `xorm:"notnull index pk"` // FK:Fund.ID
+	ExDate Date   `xorm:"notnull pk"`
+	Amount int64  `xorm:"bigint not null"` // Yen per 10,000 units
+}
+
+func (Distribution) TableName() string {
+	return "distributions"
+}
+
+// Account is an account which holds funds.
+type Account struct {
+	ID   string `xorm:"pk"`
+	Name string `xorm:"notnull"`
+}
+
+func (Account) TableName() string {
+	return "accounts"
+}
+
+// Types of Transaction.
+const (
+	TxnBuy      = "buy"
+	TxnSell     = "sell"
+	TxnReinvest = "reinvest" // reinvested distribution
+	TxnFee      = "fee"
+)
+
+// TxnTypes is the list of all types of Transaction.
+var TxnTypes = []string{TxnBuy, TxnSell, TxnReinvest, TxnFee}
+
+// Transaction is a trade of a fund in an account.
+type Transaction struct {
+	ID        int64  `xorm:"pk autoincr"`
+	AccountID string `xorm:"notnull index"` // FK:Account.ID
+	FundID    string `xorm:"notnull index"` // FK:Fund.ID
+	Date      Date   `xorm:"notnull"`
+	Type      string `xorm:"notnull"`
+	Units     int64  `xorm:"bigint notnull"` // Units (口)
+	Amount    int64  `xorm:"bigint notnull"` // Yen
+	Note      string `xorm:"null"`
+}
+
+func (Transaction) TableName() string {
+	return "transactions"
+}
+
+// FundHoliday is a day when a fund publishes no NAV, though it is a
+// business day in Japan. For example, a holiday of foreign markets.
+type FundHoliday struct {
+	FundID string `xorm:"notnull index pk"` // FK:Fund.ID
+	Date   Date   `xorm:"notnull pk"`
+	Note   string `xorm:"null"`
+}
+
+func (FundHoliday) TableName() string {
+	return "fund_holidays"
+}
+
+// FundMetric is a figure which a provider reports for a fund on a date, like
+// risk or returns.
+type FundMetric struct {
+	FundID  string          `xorm:"notnull index pk"` // FK:Fund.ID
+	Date    Date            `xorm:"notnull pk"`
+	Name    string          `xorm:"notnull pk"`
+	Value   decimal.Decimal `xorm:"notnull"`
+	RefDate Date            `xorm:"null"` // Date which the value refers to
+}
+
+func (FundMetric) TableName() string {
+	return "fund_metrics"
+}
+
+var Beans = []any{&Fund{}, &Price{}, &FetchRun{}, &FetchResult{}, &Distribution{}, &Account{}, &Transaction{}, &FundHoliday{}, &FundMetric{}}
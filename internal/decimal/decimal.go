@@ -0,0 +1,73 @@
+// Package decimal provides decimal numbers which keep digits as reported by
+// providers, without rounding errors of floating point numbers.
+package decimal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Decimal is a decimal number in a normalized form, like "-1.02" or "24400".
+// It is stored as TEXT in DB.
+type Decimal string
+
+// Parse parses a decimal number with optional sign and comma separators,
+// like "+1,234.50". Digits of the fraction are kept, even trailing zeros.
+func Parse(s string) (Decimal, error) {
+	orig := s
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	neg := false
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	ipart, fpart, hasDot := strings.Cut(s, ".")
+	if (ipart == "" && fpart == "") || !isDigits(ipart) || !isDigits(fpart) || (hasDot && fpart == "") {
+		return "", fmt.Errorf("invalid decimal %q", orig)
+	}
+	ipart = strings.TrimLeft(ipart, "0")
+	if ipart == "" {
+		ipart = "0"
+	}
+	if strings.Trim(ipart+fpart, "0") == "" {
+		neg = false
+	}
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	b.WriteString(ipart)
+	if fpart != "" {
+		b.WriteByte('.')
+		b.WriteString(fpart)
+	}
+	return Decimal(b.String()), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (d Decimal) String() string {
+	return string(d)
+}
+
+// Float64 converts d to a float64, which may lose precision.
+func (d Decimal) Float64() float64 {
+	v, _ := strconv.ParseFloat(string(d), 64)
+	return v
+}
+
+// MarshalJSON writes d as a JSON number, or null when d is empty.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("null"), nil
+	}
+	return []byte(d), nil
+}
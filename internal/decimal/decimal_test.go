@@ -0,0 +1,44 @@
+package decimal_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/koron/funddb/internal/decimal"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want decimal.Decimal
+	}{
+		{"24400", "24400"},
+		{"11.52", "11.52"},
+		{"-1.02", "-1.02"},
+		{"+2.50", "2.50"},
+		{" 1,234.5 ", "1234.5"},
+		{"007", "7"},
+		{".5", "0.5"},
+		{"-0.00", "0.00"},
+	} {
+		got, err := decimal.Parse(tc.s)
+		if err != nil {
+			t.Errorf("failed to parse %q: %s", tc.s, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("unmatch decimal for %q: want=%s got=%s", tc.s, tc.want, got)
+		}
+	}
+	for _, s := range []string{"", "-", ".", "1.", "1.2.3", "1e3", "N/A", "--1"} {
+		if _, err := decimal.Parse(s); err == nil {
+			t.Errorf("no errors for %q", s)
+		}
+	}
+	if v := decimal.Decimal("-2.5").Float64(); v != -2.5 {
+		t.Errorf("unexpected float: %f", v)
+	}
+	if b, err := json.Marshal([]decimal.Decimal{"11.50", ""}); err != nil || string(b) != "[11.50,null]" {
+		t.Errorf("unexpected JSON: %s %v", b, err)
+	}
+}
@@ -0,0 +1,205 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/koron/funddb/internal/calendar"
+	"github.com/koron/funddb/internal/dataobj"
+	"xorm.io/xorm"
+)
+
+// Daemon fetches latest prices on a schedule, and re-polls funds until
+// their new prices arrive. New prices are ones of the latest business day
+// when Schedule has a calendar, or ones after stored prices otherwise.
+type Daemon struct {
+	ORM     *xorm.Engine
+	Fetcher *Fetcher
+
+	Schedule Schedule
+
+	// IDs are fund IDs to fetch. All funds with fetch ID are fetched when
+	// empty.
+	IDs []string
+
+	// PollInterval is an interval to re-poll funds which got no new prices.
+	PollInterval time.Duration
+
+	// PollDuration is a duration to keep re-polling since a start of a
+	// batch. Funds which got no new prices in this duration are left to the
+	// next batch.
+	PollDuration time.Duration
+
+	// Logger logs progress of batches. log.Default() is used when nil.
+	Logger *log.Logger
+}
+
+func (d *Daemon) logger() *log.Logger {
+	if d.Logger == nil {
+		return log.Default()
+	}
+	return d.Logger
+}
+
+// sleep waits for a duration or cancellation of ctx.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Run runs batches on the schedule until ctx is canceled. It returns nil
+// when stopped by ctx.
+func (d *Daemon) Run(ctx context.Context) error {
+	for {
+		next := d.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return errors.New("no scheduled time")
+		}
+		d.logger().Printf("next batch at %s", next.Format(time.RFC3339))
+		if err := sleep(ctx, time.Until(next)); err != nil {
+			return nil
+		}
+		err := d.RunBatch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// latestDates returns dates of the latest stored prices for targets.
+func latestDates(orm *xorm.Engine, targets []Target) (map[string]dataobj.Date, error) {
+	dates := map[string]dataobj.Date{}
+	for _, t := range targets {
+		var p dataobj.Price
+		ok, err := orm.Where("id = ?", t.FundID).Desc("date").Get(&p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			dates[t.FundID] = p.Date
+		}
+	}
+	return dates, nil
+}
+
+// expectedDates returns the latest business day on or before a date for
+// each target, with holidays of its fund.
+func expectedDates(orm *xorm.Engine, cal *calendar.Calendar, targets []Target, d dataobj.Date) (map[string]dataobj.Date, error) {
+	ids := make([]string, len(targets))
+	for i, t := range targets {
+		ids[i] = t.FundID
+	}
+	var list []dataobj.FundHoliday
+	if err := orm.In("fund_id", ids).Find(&list); err != nil {
+		return nil, err
+	}
+	holidays := map[string]map[dataobj.Date]string{}
+	for _, h := range list {
+		if holidays[h.FundID] == nil {
+			holidays[h.FundID] = map[dataobj.Date]string{}
+		}
+		holidays[h.FundID][h.Date] = h.Note
+	}
+	dates := make(map[string]dataobj.Date, len(targets))
+	for _, t := range targets {
+		c := cal
+		if extra, ok := holidays[t.FundID]; ok {
+			c = cal.WithHolidays(extra)
+		}
+		dates[t.FundID] = c.LastBusinessDay(d)
+	}
+	return dates, nil
+}
+
+// RunBatch fetches latest prices of targets, and re-polls funds which got
+// no new prices, until all of them get or PollDuration passes. Results of
+// each poll are stored in a transaction, so a canceled batch never leaves a
+// partial write. Fetched prices are stored even when canceled, and failures
+// by the cancellation are not recorded.
+func (d *Daemon) RunBatch(ctx context.Context) error {
+	targets, err := LoadTargets(d.ORM, d.IDs)
+	if err != nil {
+		return err
+	}
+	base, err := latestDates(d.ORM, targets)
+	if err != nil {
+		return err
+	}
+	var expected map[string]dataobj.Date
+	if cal := d.Schedule.Calendar; cal != nil {
+		today := dataobj.DateFromTime(time.Now().In(d.Schedule.location()))
+		expected, err = expectedDates(d.ORM, cal, targets, today)
+		if err != nil {
+			return err
+		}
+	}
+	// arrived reports whether a fetched price is a new one for a fund.
+	arrived := func(r Result) bool {
+		date := dataobj.DateFromTime(r.Price.Date())
+		if want, ok := expected[r.FundID]; ok {
+			return date.Compare(want) >= 0
+		}
+		return date.Compare(base[r.FundID]) > 0
+	}
+	deadline := time.Now().Add(d.PollDuration)
+	for poll := 1; ; poll++ {
+		startedAt := time.Now()
+		results := d.Fetcher.FetchAll(ctx, targets)
+		canceled := ctx.Err() != nil
+		if canceled {
+			results = succeeded(results)
+		}
+		if len(results) > 0 {
+			if _, err := Store(d.ORM, startedAt, results); err != nil {
+				return err
+			}
+		}
+		if canceled {
+			return ctx.Err()
+		}
+
+		var pending []Target
+		for _, r := range results {
+			if r.Err == nil && arrived(r) {
+				continue
+			}
+			pending = append(pending, r.Target)
+		}
+		d.logger().Printf("poll #%d: %d of %d funds got new prices", poll, len(targets)-len(pending), len(targets))
+		if len(pending) == 0 {
+			return nil
+		}
+		if time.Now().Add(d.PollInterval).After(deadline) {
+			for _, t := range pending {
+				d.logger().Printf("gave up waiting for a new price: %s", t.FundID)
+			}
+			return nil
+		}
+		if err := sleep(ctx, d.PollInterval); err != nil {
+			return err
+		}
+		targets = pending
+	}
+}
+
+// succeeded filters succeeded results.
+func succeeded(results []Result) []Result {
+	var list []Result
+	for _, r := range results {
+		if r.Err == nil {
+			list = append(list, r)
+		}
+	}
+	return list
+}
@@ -0,0 +1,214 @@
+package fetcher_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/calendar"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/fetcher"
+	"github.com/koron/funddb/internal/fundprice"
+	"xorm.io/xorm"
+)
+
+type datedPrice struct {
+	date  dataobj.Date
+	value int64
+}
+
+func (p datedPrice) Date() time.Time  { return p.date.Time(time.UTC).Add(18 * time.Hour) }
+func (p datedPrice) Price() int64     { return p.value }
+func (p datedPrice) NetAssets() int64 { return 0 }
+
+func newTestEngine(t *testing.T) *xorm.Engine {
+	t.Helper()
+	engine, err := dataobj.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []dataobj.Fund{
+		{ID: "A", Name: "Fund A", URL: "https://example.com/a", FetchID: "test:a"},
+		{ID: "B", Name: "Fund B", URL: "https://example.com/b", FetchID: "test:b"},
+	} {
+		if _, err := engine.Insert(&f); err != nil {
+			t.Fatal(err)
+		}
+		p := dataobj.Price{ID: f.ID, Date: dataobj.NewDate(2024, 1, 9), Value: 10000}
+		if _, err := engine.Insert(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return engine
+}
+
+func TestDaemonRunBatch(t *testing.T) {
+	engine := newTestEngine(t)
+	var (
+		mu    sync.Mutex
+		polls = map[string]int{}
+	)
+	d := &fetcher.Daemon{
+		ORM: engine,
+		Fetcher: &fetcher.Fetcher{
+			Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+				mu.Lock()
+				polls[fetchID]++
+				n := polls[fetchID]
+				mu.Unlock()
+				// "a" gets a new price at first, "b" fails once and gets
+				// a new price at the third poll.
+				switch {
+				case fetchID == "test:a":
+					return datedPrice{dataobj.NewDate(2024, 1, 10), 10100}, nil
+				case n == 1:
+					return nil, errors.New("failure")
+				case n == 2:
+					return datedPrice{dataobj.NewDate(2024, 1, 9), 10000}, nil
+				default:
+					return datedPrice{dataobj.NewDate(2024, 1, 10), 9900}, nil
+				}
+			},
+		},
+		PollInterval: 10 * time.Millisecond,
+		PollDuration: time.Second,
+		Logger:       log.New(io.Discard, "", 0),
+	}
+	if err := d.RunBatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if polls["test:a"] != 1 || polls["test:b"] != 3 {
+		t.Errorf("unexpected polls: %v", polls)
+	}
+	var prices []dataobj.Price
+	if err := engine.Where("date = ?", dataobj.NewDate(2024, 1, 10)).OrderBy("id").Find(&prices); err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 2 || prices[0].Value != 10100 || prices[1].Value != 9900 {
+		t.Errorf("unexpected prices: %+v", prices)
+	}
+	if n, err := engine.Count(&dataobj.FetchRun{}); err != nil || n != 3 {
+		t.Errorf("unexpected number of runs: %d %v", n, err)
+	}
+}
+
+func TestDaemonRunBatchExpected(t *testing.T) {
+	engine := newTestEngine(t)
+	loc := time.FixedZone("JST", 9*60*60)
+	cal := calendar.Default()
+	today := cal.LastBusinessDay(dataobj.DateFromTime(time.Now().In(loc)))
+	prev := cal.LastBusinessDay(today.AddDays(-1))
+	// "A" has the price of today already, and "B" has no prices of today,
+	// as it is a holiday of the fund.
+	if _, err := engine.Insert(&dataobj.Price{ID: "A", Date: today, Value: 10100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Insert(&dataobj.FundHoliday{FundID: "B", Date: today}); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu    sync.Mutex
+		polls = map[string]int{}
+	)
+	d := &fetcher.Daemon{
+		ORM:      engine,
+		Schedule: fetcher.Schedule{Location: loc, Calendar: cal},
+		Fetcher: &fetcher.Fetcher{
+			Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+				mu.Lock()
+				polls[fetchID]++
+				n := polls[fetchID]
+				mu.Unlock()
+				// "B" gets an old price at first, which is newer than the
+				// stored one but is not of the expected date.
+				switch {
+				case fetchID == "test:a":
+					return datedPrice{today, 10100}, nil
+				case n == 1:
+					return datedPrice{dataobj.NewDate(2024, 1, 10), 9900}, nil
+				default:
+					return datedPrice{prev, 9900}, nil
+				}
+			},
+		},
+		PollInterval: 10 * time.Millisecond,
+		PollDuration: time.Second,
+		Logger:       log.New(io.Discard, "", 0),
+	}
+	if err := d.RunBatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if polls["test:a"] != 1 || polls["test:b"] != 2 {
+		t.Errorf("unexpected polls: %v", polls)
+	}
+}
+
+func TestDaemonRunBatchGiveUp(t *testing.T) {
+	engine := newTestEngine(t)
+	var (
+		mu    sync.Mutex
+		polls int
+	)
+	d := &fetcher.Daemon{
+		ORM: engine,
+		IDs: []string{"B"},
+		Fetcher: &fetcher.Fetcher{
+			Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+				mu.Lock()
+				polls++
+				mu.Unlock()
+				return datedPrice{dataobj.NewDate(2024, 1, 9), 10000}, nil
+			},
+		},
+		PollInterval: 20 * time.Millisecond,
+		PollDuration: 50 * time.Millisecond,
+		Logger:       log.New(io.Discard, "", 0),
+	}
+	if err := d.RunBatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if polls < 2 || polls > 3 {
+		t.Errorf("unexpected polls: %d", polls)
+	}
+}
+
+func TestDaemonRunBatchCanceled(t *testing.T) {
+	engine := newTestEngine(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &fetcher.Daemon{
+		ORM: engine,
+		Fetcher: &fetcher.Fetcher{
+			Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+				if fetchID == "test:a" {
+					return datedPrice{dataobj.NewDate(2024, 1, 10), 10100}, nil
+				}
+				// cancel in middle of a batch.
+				cancel()
+				return nil, ctx.Err()
+			},
+		},
+		PollInterval: time.Hour,
+		PollDuration: time.Hour,
+		Logger:       log.New(io.Discard, "", 0),
+	}
+	if err := d.RunBatch(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the fetched price is stored, and the canceled one is not recorded.
+	var results []dataobj.FetchResult
+	if err := engine.Find(&results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].FundID != "A" || results[0].Status != dataobj.FetchStatusOK {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,157 @@
+// Package fetcher fetches prices of funds concurrently.
+package fetcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/fundprice"
+)
+
+// Target is a fund to fetch price.
+type Target struct {
+	FundID  string
+	FetchID string
+}
+
+// Result is a result of fetching a Target.
+type Result struct {
+	Target
+	Price fundprice.Price
+	Err   error
+
+	// Latency is a duration of the last attempt.
+	Latency time.Duration
+}
+
+// Fetcher fetches prices of targets with a worker pool.
+type Fetcher struct {
+	// Parallel is number of workers. 1 is used when zero or negative.
+	Parallel int
+
+	// Interval is the minimum interval between starts of requests for a
+	// scheme, which is bound to a host of a provider.
+	Interval time.Duration
+
+	// Fetch retrieves a price for a fetch ID. adapter.Fetch is used when
+	// nil.
+	Fetch func(ctx context.Context, fetchID string) (fundprice.Price, error)
+
+	// Retry retries Fetch on retryable errors. Each attempt waits for
+	// Interval.
+	Retry adapter.RetryPolicy
+
+	// Progress is called with each result in order of targets, when not nil.
+	Progress func(Result)
+}
+
+// FetchAll fetches prices for all targets, and returns results in order of
+// targets.
+func (f *Fetcher) FetchAll(ctx context.Context, targets []Target) []Result {
+	fetch := f.Fetch
+	if fetch == nil {
+		fetch = adapter.Fetch
+	}
+	parallel := max(f.Parallel, 1)
+	lim := newLimiter(f.Interval)
+
+	results := make([]Result, len(targets))
+	done := make([]chan struct{}, len(targets))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = f.fetchOne(ctx, lim, fetch, targets[i])
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range targets {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	// report progress in order of targets.
+	for i := range targets {
+		<-done[i]
+		if f.Progress != nil {
+			f.Progress(results[i])
+		}
+	}
+	wg.Wait()
+	return results
+}
+
+func (f *Fetcher) fetchOne(ctx context.Context, lim *limiter, fetch func(context.Context, string) (fundprice.Price, error), t Target) Result {
+	r := Result{Target: t}
+	scheme, _, err := adapter.ParseFetchID(t.FetchID)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Err = f.Retry.Do(ctx, func(ctx context.Context) error {
+		if err := lim.wait(ctx, scheme); err != nil {
+			return err
+		}
+		start := time.Now()
+		var err error
+		r.Price, err = fetch(ctx, t.FetchID)
+		r.Latency = time.Since(start)
+		return err
+	})
+	return r
+}
+
+// limiter keeps intervals between events for each key.
+type limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newLimiter(interval time.Duration) *limiter {
+	return &limiter{
+		interval: interval,
+		next:     map[string]time.Time{},
+	}
+}
+
+// wait waits for a slot of the key.
+func (l *limiter) wait(ctx context.Context, key string) error {
+	if l.interval <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[key]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[key] = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package fetcher_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/fetcher"
+	"github.com/koron/funddb/internal/fundprice"
+)
+
+type testPrice int64
+
+func (p testPrice) Date() time.Time  { return time.Time{} }
+func (p testPrice) Price() int64     { return int64(p) }
+func (p testPrice) NetAssets() int64 { return 0 }
+
+func TestFetchAll(t *testing.T) {
+	var targets []fetcher.Target
+	for i := range 20 {
+		targets = append(targets, fetcher.Target{
+			FundID:  fmt.Sprint(i),
+			FetchID: fmt.Sprintf("s%d:%d", i%2, i),
+		})
+	}
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+	)
+	var progress []string
+	f := &fetcher.Fetcher{
+		Parallel: 4,
+		Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			// later targets finish earlier.
+			var n int64
+			fmt.Sscanf(fetchID[3:], "%d", &n)
+			time.Sleep(time.Duration(20-n) * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if n%5 == 0 {
+				return nil, errors.New("failure")
+			}
+			return testPrice(n), nil
+		},
+		Progress: func(r fetcher.Result) {
+			progress = append(progress, r.FundID)
+		},
+	}
+	results := f.FetchAll(context.Background(), targets)
+	if len(results) != len(targets) {
+		t.Fatalf("unmatch number of results: want=%d got=%d", len(targets), len(results))
+	}
+	for i, r := range results {
+		if r.FundID != targets[i].FundID {
+			t.Errorf("unmatch order #%d: want=%s got=%s", i, targets[i].FundID, r.FundID)
+		}
+		if progress[i] != targets[i].FundID {
+			t.Errorf("unmatch progress order #%d: want=%s got=%s", i, targets[i].FundID, progress[i])
+		}
+		if i%5 == 0 {
+			if r.Err == nil {
+				t.Errorf("no errors #%d", i)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("unexpected error #%d: %s", i, r.Err)
+			continue
+		}
+		if got := r.Price.Price(); got != int64(i) {
+			t.Errorf("unmatch price #%d: got=%d", i, got)
+		}
+	}
+	if peak > 4 {
+		t.Errorf("too many concurrent fetches: %d", peak)
+	}
+}
+
+func TestFetchAllInterval(t *testing.T) {
+	targets := []fetcher.Target{
+		{FundID: "1", FetchID: "a:1"},
+		{FundID: "2", FetchID: "a:2"},
+		{FundID: "3", FetchID: "a:3"},
+		{FundID: "4", FetchID: "b:4"},
+	}
+	var (
+		mu     sync.Mutex
+		starts = map[string][]time.Time{}
+	)
+	f := &fetcher.Fetcher{
+		Parallel: 4,
+		Interval: 50 * time.Millisecond,
+		Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+			mu.Lock()
+			starts[fetchID[:1]] = append(starts[fetchID[:1]], time.Now())
+			mu.Unlock()
+			return testPrice(0), nil
+		},
+	}
+	f.FetchAll(context.Background(), targets)
+	a := starts["a"]
+	if len(a) != 3 {
+		t.Fatalf("unexpected starts for scheme a: %d", len(a))
+	}
+	for i := 1; i < len(a); i++ {
+		if d := a[i].Sub(a[i-1]); d < 45*time.Millisecond {
+			t.Errorf("too short interval #%d: %s", i, d)
+		}
+	}
+	if b := starts["b"]; len(b) != 1 || b[0].Sub(a[0]) > 40*time.Millisecond {
+		t.Errorf("scheme b should not wait for scheme a")
+	}
+}
+
+func TestFetchAllRetry(t *testing.T) {
+	var starts []time.Time
+	f := &fetcher.Fetcher{
+		Interval: 50 * time.Millisecond,
+		Retry:    adapter.RetryPolicy{Retries: 3, Wait: time.Millisecond},
+		Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+			starts = append(starts, time.Now())
+			if len(starts) < 3 {
+				return nil, &adapter.StatusError{StatusCode: 503, Err: errors.New("unavailable")}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return testPrice(0), nil
+		},
+	}
+	results := f.FetchAll(context.Background(), []fetcher.Target{{FundID: "1", FetchID: "a:1"}})
+	if len(starts) != 3 || results[0].Err != nil {
+		t.Fatalf("should succeed at 3rd attempt: n=%d err=%v", len(starts), results[0].Err)
+	}
+	// retries wait for the interval too.
+	for i := 1; i < len(starts); i++ {
+		if d := starts[i].Sub(starts[i-1]); d < 45*time.Millisecond {
+			t.Errorf("too short interval #%d: %s", i, d)
+		}
+	}
+	// the latency is of the last attempt, without waits.
+	if l := results[0].Latency; l < 10*time.Millisecond || l >= 45*time.Millisecond {
+		t.Errorf("unexpected latency: %s", l)
+	}
+}
+
+func TestFetchAllCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f := &fetcher.Fetcher{
+		Interval: time.Hour,
+		Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+			return testPrice(0), nil
+		},
+	}
+	results := f.FetchAll(ctx, []fetcher.Target{{FundID: "1", FetchID: "a:1"}, {FundID: "2", FetchID: "a:2"}})
+	for i, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("unexpected error #%d: %v", i, r.Err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package fetcher
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/koron/funddb/internal/calendar"
+	"github.com/koron/funddb/internal/dataobj"
+)
+
+// Schedule is a time of day to start fetching, on some days of the week.
+type Schedule struct {
+	// Weekdays are days to fetch. All days are used when empty.
+	Weekdays []time.Weekday
+
+	// At is an offset from the midnight to start fetching.
+	At time.Duration
+
+	// Location is a time zone of the schedule. UTC is used when nil.
+	Location *time.Location
+
+	// Calendar skips days which are not business days, like national
+	// holidays, when it is not nil.
+	Calendar *calendar.Calendar
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseWeekdays parses comma separated names or ranges of weekdays, like
+// "mon-fri" or "mon,wed,fri".
+func ParseWeekdays(s string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, item := range strings.Split(strings.ToLower(s), ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(item), "-")
+		d0, ok := weekdayNames[first]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday: %q", first)
+		}
+		d1 := d0
+		if isRange {
+			d1, ok = weekdayNames[last]
+			if !ok {
+				return nil, fmt.Errorf("unknown weekday: %q", last)
+			}
+		}
+		for d := d0; ; d = (d + 1) % 7 {
+			if !slices.Contains(days, d) {
+				days = append(days, d)
+			}
+			if d == d1 {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// ParseTimeOfDay parses a time of day in "HH:MM" format, and returns an
+// offset from the midnight.
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", s, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// maxScheduleDays is the number of days to search the next scheduled time.
+// It covers a week, and long holidays like year-end closures.
+const maxScheduleDays = 32
+
+func (s Schedule) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+// Next returns the first scheduled time after t.
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := s.location()
+	t = t.In(loc)
+	y, m, d := t.Date()
+	for i := range maxScheduleDays {
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, loc)
+		if len(s.Weekdays) > 0 && !slices.Contains(s.Weekdays, day.Weekday()) {
+			continue
+		}
+		if s.Calendar != nil && !s.Calendar.IsBusinessDay(dataobj.DateFromTime(day)) {
+			continue
+		}
+		at := day.Add(s.At)
+		if at.After(t) {
+			return at
+		}
+	}
+	// never reached when Weekdays have valid values.
+	return time.Time{}
+}
@@ -0,0 +1,90 @@
+package fetcher_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/calendar"
+	"github.com/koron/funddb/internal/fetcher"
+)
+
+func TestParseWeekdays(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []time.Weekday
+	}{
+		{"mon-fri", []time.Weekday{1, 2, 3, 4, 5}},
+		{"Mon,Wed,fri", []time.Weekday{1, 3, 5}},
+		{"fri-mon", []time.Weekday{5, 6, 0, 1}},
+		{"sun", []time.Weekday{0}},
+	} {
+		got, err := fetcher.ParseWeekdays(tc.in)
+		if err != nil {
+			t.Errorf("failed to parse %q: %s", tc.in, err)
+			continue
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("unmatch weekdays for %q: want=%v got=%v", tc.in, tc.want, got)
+		}
+	}
+	for _, s := range []string{"", "mon-", "monday", "mon,xyz"} {
+		if _, err := fetcher.ParseWeekdays(s); err == nil {
+			t.Errorf("no errors for %q", s)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	s := fetcher.Schedule{
+		Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		At:       18 * time.Hour,
+		Location: loc,
+	}
+	for _, tc := range []struct {
+		now  time.Time
+		want time.Time
+	}{
+		// Wednesday morning
+		{time.Date(2024, 1, 10, 9, 0, 0, 0, loc), time.Date(2024, 1, 10, 18, 0, 0, 0, loc)},
+		// exactly on the time
+		{time.Date(2024, 1, 10, 18, 0, 0, 0, loc), time.Date(2024, 1, 11, 18, 0, 0, 0, loc)},
+		// Friday night to Monday
+		{time.Date(2024, 1, 12, 20, 0, 0, 0, loc), time.Date(2024, 1, 15, 18, 0, 0, 0, loc)},
+		// Saturday in UTC
+		{time.Date(2024, 1, 13, 12, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 18, 0, 0, 0, loc)},
+	} {
+		got := s.Next(tc.now)
+		if !got.Equal(tc.want) {
+			t.Errorf("unmatch next of %s: want=%s got=%s", tc.now, tc.want, got)
+		}
+	}
+}
+
+func TestScheduleNextHolidays(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	s := fetcher.Schedule{
+		Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		At:       18 * time.Hour,
+		Location: loc,
+		Calendar: calendar.Default(),
+	}
+	for _, tc := range []struct {
+		now  time.Time
+		want time.Time
+	}{
+		// year-end closures and the new year's day (Wed), to Monday
+		{time.Date(2024, 12, 30, 20, 0, 0, 0, loc), time.Date(2025, 1, 6, 18, 0, 0, 0, loc)},
+		{time.Date(2025, 1, 1, 9, 0, 0, 0, loc), time.Date(2025, 1, 6, 18, 0, 0, 0, loc)},
+		// Friday night over the coming of age day (Mon), to Tuesday
+		{time.Date(2025, 1, 10, 20, 0, 0, 0, loc), time.Date(2025, 1, 14, 18, 0, 0, 0, loc)},
+		// business days are not affected
+		{time.Date(2025, 1, 14, 9, 0, 0, 0, loc), time.Date(2025, 1, 14, 18, 0, 0, 0, loc)},
+	} {
+		got := s.Next(tc.now)
+		if !got.Equal(tc.want) {
+			t.Errorf("unmatch next of %s: want=%s got=%s", tc.now, tc.want, got)
+		}
+	}
+}
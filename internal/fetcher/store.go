@@ -0,0 +1,158 @@
+package fetcher
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/fundprice"
+	"github.com/koron/funddb/internal/xormhelper"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// CheckAssociationCode checks the association code which a price reports
+// matches with the ID of a fund. It returns nil when the price doesn't
+// report it.
+func CheckAssociationCode(fundID string, p fundprice.Price) error {
+	ac, ok := p.(fundprice.AssociationCoder)
+	if !ok {
+		return nil
+	}
+	if code := ac.AssociationCode(); code != "" && code != fundID {
+		return fmt.Errorf("association code %s of fetched data doesn't match with fund ID=%s", code, fundID)
+	}
+	return nil
+}
+
+// LoadTargets loads funds which have fetch ID as fetch targets. All funds
+// are loaded when ids is empty.
+func LoadTargets(orm *xorm.Engine, ids []string) ([]Target, error) {
+	session := orm.Where("fetch_id IS NOT NULL AND fetch_id != ''").OrderBy("id")
+	if len(ids) > 0 {
+		session.In("id", ids)
+	}
+	var fundList []dataobj.Fund
+	if err := session.Find(&fundList); err != nil {
+		return nil, err
+	}
+	targets := make([]Target, len(fundList))
+	for i, fund := range fundList {
+		targets[i] = Target{FundID: fund.ID, FetchID: adapter.ExpandFetchID(fund.FetchID, fund.ID)}
+	}
+	return targets, nil
+}
+
+// Store puts prices of succeeded results into DB, and records a fetch run
+// with outcomes of all results, in a transaction.
+func Store(orm *xorm.Engine, startedAt time.Time, results []Result) (*dataobj.FetchRun, error) {
+	run := dataobj.FetchRun{
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Total:      len(results),
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			run.Failed++
+		}
+	}
+	err := xormhelper.Tx(orm, func(session *xorm.Session) error {
+		if _, err := session.Insert(&run); err != nil {
+			return err
+		}
+		for _, r := range results {
+			fr := dataobj.FetchResult{
+				RunID:       run.ID,
+				FundID:      r.FundID,
+				FetchID:     r.FetchID,
+				LatencyMsec: r.Latency.Milliseconds(),
+			}
+			if r.Err != nil {
+				fr.Status = dataobj.FetchStatusError
+				fr.Error = r.Err.Error()
+				if _, err := session.Insert(&fr); err != nil {
+					return err
+				}
+				continue
+			}
+			pd := dataobj.Price{
+				ID:        r.FundID,
+				Date:      dataobj.DateFromTime(r.Price.Date()),
+				Value:     r.Price.Price(),
+				NetAssets: r.Price.NetAssets(),
+			}
+			if err := CheckAssociationCode(r.FundID, r.Price); err != nil {
+				log.Printf("WARN: %s", err)
+			}
+			pk := schemas.PK{pd.ID, pd.Date}
+			if err := xormhelper.UpsertOne(session, pk, pd); err != nil {
+				return err
+			}
+			if err := storeDistributions(session, r.FundID, r.Price); err != nil {
+				return err
+			}
+			if err := StoreMetrics(session, r.FundID, r.Price); err != nil {
+				return err
+			}
+			fr.Status = dataobj.FetchStatusOK
+			fr.Date = pd.Date
+			fr.Price = pd.Value
+			if _, err := session.Insert(&fr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// storeDistributions puts distributions into DB, when a price reports them.
+func storeDistributions(session *xorm.Session, fundID string, p fundprice.Price) error {
+	d, ok := p.(fundprice.Distributor)
+	if !ok {
+		return nil
+	}
+	for _, dist := range d.Distributions() {
+		dd := dataobj.Distribution{
+			ID:     fundID,
+			ExDate: dataobj.DateFromTime(dist.ExDate),
+			Amount: dist.Amount,
+		}
+		pk := schemas.PK{dd.ID, dd.ExDate}
+		if err := xormhelper.UpsertOne(session, pk, dd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StoreMetrics puts metrics into DB with the date of a price, when the
+// price reports them.
+func StoreMetrics(session *xorm.Session, fundID string, p fundprice.Price) error {
+	mr, ok := p.(fundprice.MetricReporter)
+	if !ok {
+		return nil
+	}
+	date := dataobj.DateFromTime(p.Date())
+	for name, m := range mr.Metrics() {
+		fm := dataobj.FundMetric{
+			FundID: fundID,
+			Date:   date,
+			Name:   name,
+			Value:  m.Value,
+		}
+		if !m.RefDate.IsZero() {
+			fm.RefDate = dataobj.DateFromTime(m.RefDate)
+		}
+		pk := schemas.PK{fm.FundID, fm.Date, fm.Name}
+		if err := xormhelper.UpsertOne(session, pk, fm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package fetcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/decimal"
+	"github.com/koron/funddb/internal/fetcher"
+	"github.com/koron/funddb/internal/fundprice"
+)
+
+type metricPrice struct {
+	datedPrice
+	metrics map[string]fundprice.Metric
+}
+
+func (p metricPrice) Metrics() map[string]fundprice.Metric { return p.metrics }
+
+func TestStoreMetrics(t *testing.T) {
+	engine := newTestEngine(t)
+	date := dataobj.NewDate(2024, 1, 10)
+	store := func(risk decimal.Decimal) {
+		t.Helper()
+		p := metricPrice{
+			datedPrice: datedPrice{date, 10100},
+			metrics: map[string]fundprice.Metric{
+				"risk_1y":    {Value: risk},
+				"nav_max_1y": {Value: "10200", RefDate: time.Date(2023, 12, 28, 0, 0, 0, 0, time.UTC)},
+			},
+		}
+		_, err := fetcher.Store(engine, time.Now(), []fetcher.Result{
+			{Target: fetcher.Target{FundID: "A", FetchID: "test:a"}, Price: p},
+			{Target: fetcher.Target{FundID: "B", FetchID: "test:b"}, Price: datedPrice{date, 9900}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store("11.50")
+	store("11.52")
+
+	var list []dataobj.FundMetric
+	if err := engine.OrderBy("fund_id, name").Find(&list); err != nil {
+		t.Fatal(err)
+	}
+	want := []dataobj.FundMetric{
+		{FundID: "A", Date: date, Name: "nav_max_1y", Value: "10200", RefDate: dataobj.NewDate(2023, 12, 28)},
+		{FundID: "A", Date: date, Name: "risk_1y", Value: "11.52"},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("unexpected metrics: %+v", list)
+	}
+	for i := range want {
+		if list[i] != want[i] {
+			t.Errorf("unmatch metric #%d: want=%+v got=%+v", i, want[i], list[i])
+		}
+	}
+}
+
+type codedPrice struct {
+	datedPrice
+	code string
+}
+
+func (p codedPrice) AssociationCode() string { return p.code }
+
+func TestCheckAssociationCode(t *testing.T) {
+	p := datedPrice{dataobj.NewDate(2024, 1, 10), 10100}
+	for i, c := range []struct {
+		price   fundprice.Price
+		wantErr bool
+	}{
+		{p, false},
+		{codedPrice{p, ""}, false},
+		{codedPrice{p, "0331418A"}, false},
+		{codedPrice{p, "03311187"}, true},
+	} {
+		err := fetcher.CheckAssociationCode("0331418A", c.price)
+		if (err != nil) != c.wantErr {
+			t.Errorf("#%d unexpected error: %v", i, err)
+		}
+	}
+}
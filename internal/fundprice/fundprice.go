@@ -1,6 +1,11 @@
 package fundprice
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/koron/funddb/internal/decimal"
+)
 
 type Price interface {
 	Date() time.Time
@@ -8,3 +13,45 @@ type Price interface {
 
 	NetAssets() int64
 }
+
+// History is an optional interface for adapters which can retrieve a dated
+// series of prices.
+type History interface {
+	// History retrieves prices of a fund from "from" to "to" inclusive.
+	History(ctx context.Context, id string, from, to time.Time) ([]Price, error)
+}
+
+// Distribution is a distribution (dividend) of a fund.
+type Distribution struct {
+	ExDate time.Time
+	// Amount is yen per 10,000 units, same as prices.
+	Amount int64
+}
+
+// Distributor is an optional interface for Price which reports recent
+// distributions of a fund.
+type Distributor interface {
+	Distributions() []Distribution
+}
+
+// Metric is a figure which a provider reports for a fund, like risk or
+// returns.
+type Metric struct {
+	Value decimal.Decimal
+	// RefDate is a date which the value refers to, like a date of the
+	// highest price. It is zero when not available.
+	RefDate time.Time
+}
+
+// MetricReporter is an optional interface for Price which reports metrics
+// of a fund, keyed by names of metrics.
+type MetricReporter interface {
+	Metrics() map[string]Metric
+}
+
+// AssociationCoder is an optional interface for Price which reports the
+// association fund code (協会コード) of a fund. It is cross-checked with the
+// ID of the fund, which is the association code.
+type AssociationCoder interface {
+	AssociationCode() string
+}
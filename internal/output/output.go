@@ -0,0 +1,197 @@
+// Package output writes rows of values in several formats.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format is a name of output format.
+type Format string
+
+const (
+	Table Format = "table"
+	TSV   Format = "tsv"
+	CSV   Format = "csv"
+	JSON  Format = "json"
+	JSONL Format = "jsonl"
+)
+
+// Formats is the list of all supported formats.
+var Formats = []Format{Table, TSV, CSV, JSON, JSONL}
+
+// ParseFormat parses a name of format.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q, available formats are: %s", s, FormatNames())
+}
+
+// FormatNames returns names of all supported formats joined by "|".
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, "|")
+}
+
+// Writer writes a header and rows. Values of rows are formatted by fmt
+// package for text formats, or encoding/json package for JSON formats. nil
+// values are written as empty or null.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []any) error
+	// Flush writes buffered data and closing delimiters when needed.
+	Flush() error
+}
+
+// NewWriter creates a Writer for a format.
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case Table:
+		return &textWriter{tw: tabwriter.NewWriter(w, 0, 8, 1, ' ', 0), null: "-"}, nil
+	case TSV:
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+		return &csvWriter{cw: cw}, nil
+	case CSV:
+		return &csvWriter{cw: csv.NewWriter(w)}, nil
+	case JSON:
+		return &jsonWriter{w: w, array: true}, nil
+	case JSONL:
+		return &jsonWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", f)
+	}
+}
+
+// Text formats a value for text formats.
+func Text(v any, null string) string {
+	switch x := v.(type) {
+	case nil:
+		return null
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type textWriter struct {
+	tw   *tabwriter.Writer
+	null string
+}
+
+func (w *textWriter) WriteHeader(columns []string) error {
+	_, err := fmt.Fprintln(w.tw, strings.ToUpper(strings.Join(columns, "\t")))
+	return err
+}
+
+func (w *textWriter) WriteRow(values []any) error {
+	cols := make([]string, len(values))
+	for i, v := range values {
+		cols[i] = Text(v, w.null)
+	}
+	_, err := fmt.Fprintln(w.tw, strings.Join(cols, "\t"))
+	return err
+}
+
+func (w *textWriter) Flush() error {
+	return w.tw.Flush()
+}
+
+type csvWriter struct {
+	cw *csv.Writer
+}
+
+func (w *csvWriter) WriteHeader(columns []string) error {
+	return w.cw.Write(columns)
+}
+
+func (w *csvWriter) WriteRow(values []any) error {
+	cols := make([]string, len(values))
+	for i, v := range values {
+		cols[i] = Text(v, "")
+	}
+	return w.cw.Write(cols)
+}
+
+func (w *csvWriter) Flush() error {
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+// jsonWriter writes rows as JSON objects, which keep order of columns.
+type jsonWriter struct {
+	w       io.Writer
+	array   bool
+	columns []string
+	n       int
+}
+
+func (w *jsonWriter) WriteHeader(columns []string) error {
+	w.columns = columns
+	return nil
+}
+
+func (w *jsonWriter) WriteRow(values []any) error {
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("number of values %d doesn't match with columns %d", len(values), len(w.columns))
+	}
+	var b bytes.Buffer
+	if w.array {
+		if w.n == 0 {
+			b.WriteString("[\n")
+		} else {
+			b.WriteString(",\n")
+		}
+	}
+	b.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(w.columns[i])
+		if err != nil {
+			return err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	}
+	b.WriteByte('}')
+	if !w.array {
+		b.WriteByte('\n')
+	}
+	w.n++
+	_, err := w.w.Write(b.Bytes())
+	return err
+}
+
+func (w *jsonWriter) Flush() error {
+	if !w.array {
+		return nil
+	}
+	s := "\n]\n"
+	if w.n == 0 {
+		s = "[]\n"
+	}
+	_, err := io.WriteString(w.w, s)
+	return err
+}
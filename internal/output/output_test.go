@@ -0,0 +1,72 @@
+package output_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/koron/funddb/internal/output"
+)
+
+func TestWriter(t *testing.T) {
+	columns := []string{"id", "value", "rate"}
+	rows := [][]any{
+		{"A", int64(100), 1.5},
+		{"B,\"2\"", int64(-20), nil},
+	}
+	for _, c := range []struct {
+		format output.Format
+		want   string
+	}{
+		{output.Table, "ID    VALUE RATE\nA     100   1.5\nB,\"2\" -20   -\n"},
+		{output.TSV, "id\tvalue\trate\nA\t100\t1.5\n\"B,\"\"2\"\"\"\t-20\t\n"},
+		{output.CSV, "id,value,rate\nA,100,1.5\n\"B,\"\"2\"\"\",-20,\n"},
+		{output.JSON, "[\n{\"id\":\"A\",\"value\":100,\"rate\":1.5},\n{\"id\":\"B,\\\"2\\\"\",\"value\":-20,\"rate\":null}\n]\n"},
+		{output.JSONL, "{\"id\":\"A\",\"value\":100,\"rate\":1.5}\n{\"id\":\"B,\\\"2\\\"\",\"value\":-20,\"rate\":null}\n"},
+	} {
+		var b bytes.Buffer
+		w, err := output.NewWriter(&b, c.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteHeader(columns); err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rows {
+			if err := w.WriteRow(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(c.want, b.String()); d != "" {
+			t.Errorf("unmatch for %s: -want +got\n%s", c.format, d)
+		}
+	}
+}
+
+func TestEmptyJSON(t *testing.T) {
+	var b bytes.Buffer
+	w, err := output.NewWriter(&b, output.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteHeader([]string{"id"})
+	w.Flush()
+	if got := b.String(); got != "[]\n" {
+		t.Errorf("unexpected empty JSON: %q", got)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range output.Formats {
+		got, err := output.ParseFormat(string(f))
+		if err != nil || got != f {
+			t.Errorf("failed to parse %s: got=%s err=%v", f, got, err)
+		}
+	}
+	if _, err := output.ParseFormat("xml"); err == nil {
+		t.Error("no errors for unknown format")
+	}
+}
@@ -0,0 +1,320 @@
+// Package parquet writes flat tables in Apache Parquet format.
+//
+// It supports only what exporting prices requires: a flat schema of
+// required or optional columns, PLAIN encoding without compression, and
+// a data page for each column in a row group.
+package parquet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Type is a type of columns.
+type Type int
+
+const (
+	// String is a UTF-8 string, stored as BYTE_ARRAY.
+	String Type = iota
+	// Int64 is a 64-bit integer.
+	Int64
+	// Date is a date, stored as INT32 days since the Unix epoch. Values are
+	// time.Time.
+	Date
+)
+
+// Physical types, encodings and others in the Parquet metadata.
+const (
+	physicalInt32     = 1
+	physicalInt64     = 2
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	convertedUTF8 = 0
+	convertedDate = 6
+
+	// fields of the LogicalType union.
+	logicalString = 1
+	logicalDate   = 6
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	pageTypeData      = 0
+)
+
+// Column is a column in a schema.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool // allows nil values
+}
+
+// DefaultRowGroupSize is the number of rows in a row group, when it is not
+// specified.
+const DefaultRowGroupSize = 65536
+
+// Writer writes rows to a Parquet file. Rows are buffered until a row group
+// is filled, so the memory usage is bounded by the row group size.
+type Writer struct {
+	w       *bufio.Writer
+	offset  int64
+	columns []Column
+	size    int
+
+	buffers [][]any
+	groups  []rowGroup
+	rows    int64
+	err     error
+}
+
+type rowGroup struct {
+	chunks    []columnChunk
+	totalSize int64
+	rows      int64
+}
+
+type columnChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+// NewWriter creates a Writer with columns. rowGroupSize is the number of
+// rows in a row group, DefaultRowGroupSize is used when zero or negative.
+func NewWriter(w io.Writer, columns []Column, rowGroupSize int) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns")
+	}
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	pw := &Writer{
+		w:       bufio.NewWriter(w),
+		columns: columns,
+		size:    rowGroupSize,
+		buffers: make([][]any, len(columns)),
+	}
+	pw.write([]byte("PAR1"))
+	return pw, pw.err
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	w.err = err
+}
+
+// WriteRow writes a row. Types of values should match with columns:
+// string for String, int64 for Int64, time.Time for Date, or nil for
+// optional columns.
+func (w *Writer) WriteRow(values []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("number of values %d doesn't match with columns %d", len(values), len(w.columns))
+	}
+	for i, v := range values {
+		c := w.columns[i]
+		ok := false
+		switch v.(type) {
+		case nil:
+			ok = c.Optional
+		case string:
+			ok = c.Type == String
+		case int64:
+			ok = c.Type == Int64
+		case time.Time:
+			ok = c.Type == Date
+		}
+		if !ok {
+			return fmt.Errorf("invalid value for column %q: %#v", c.Name, v)
+		}
+	}
+	for i, v := range values {
+		w.buffers[i] = append(w.buffers[i], v)
+	}
+	if len(w.buffers[0]) >= w.size {
+		return w.flushRowGroup()
+	}
+	return nil
+}
+
+func (w *Writer) flushRowGroup() error {
+	n := len(w.buffers[0])
+	if n == 0 {
+		return w.err
+	}
+	g := rowGroup{rows: int64(n)}
+	for i, c := range w.columns {
+		chunk := columnChunk{offset: w.offset, numValues: int64(n)}
+		page := encodePage(c, w.buffers[i])
+		header := encodePageHeader(n, len(page))
+		w.write(header)
+		w.write(page)
+		chunk.size = int64(len(header) + len(page))
+		g.chunks = append(g.chunks, chunk)
+		g.totalSize += chunk.size
+		w.buffers[i] = w.buffers[i][:0]
+	}
+	w.groups = append(w.groups, g)
+	w.rows += int64(n)
+	return w.err
+}
+
+// encodePage encodes values of a column in a data page: definition levels
+// for optional columns, and PLAIN encoded non-nil values.
+func encodePage(c Column, values []any) []byte {
+	var b []byte
+	if c.Optional {
+		levels := encodeLevels(values)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(levels)))
+		b = append(b, levels...)
+	}
+	for _, v := range values {
+		switch x := v.(type) {
+		case string:
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(x)))
+			b = append(b, x...)
+		case int64:
+			b = binary.LittleEndian.AppendUint64(b, uint64(x))
+		case time.Time:
+			days := time.Date(x.Year(), x.Month(), x.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+			b = binary.LittleEndian.AppendUint32(b, uint32(int32(days)))
+		}
+	}
+	return b
+}
+
+// encodeLevels encodes definition levels (1 for non-nil, 0 for nil) with
+// a bit-packed run of the RLE/bit-packing hybrid encoding, bit width 1.
+func encodeLevels(values []any) []byte {
+	groups := (len(values) + 7) / 8
+	b := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups)
+	for i, v := range values {
+		if v != nil {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(b, packed...)
+}
+
+func encodePageHeader(numValues, size int) []byte {
+	var e compactEncoder
+	e.structBegin()
+	e.i32(1, pageTypeData)
+	e.i32(2, int32(size)) // uncompressed_page_size
+	e.i32(3, int32(size)) // compressed_page_size
+	e.structField(5)      // data_page_header
+	e.i32(1, int32(numValues))
+	e.i32(2, encodingPlain)
+	e.i32(3, encodingRLE) // definition_level_encoding
+	e.i32(4, encodingRLE) // repetition_level_encoding
+	e.structEnd()
+	e.structEnd()
+	return e.buf.Bytes()
+}
+
+func (c Column) physicalType() int32 {
+	switch c.Type {
+	case Int64:
+		return physicalInt64
+	case Date:
+		return physicalInt32
+	default:
+		return physicalByteArray
+	}
+}
+
+func (w *Writer) encodeFileMetaData() []byte {
+	var e compactEncoder
+	e.structBegin()
+	e.i32(1, 1) // version
+	e.listBegin(2, tStruct, len(w.columns)+1)
+	// the root of the schema.
+	e.structBegin()
+	e.binary(4, "schema")
+	e.i32(5, int32(len(w.columns)))
+	e.structEnd()
+	for _, c := range w.columns {
+		e.structBegin()
+		e.i32(1, c.physicalType())
+		rep := int32(repetitionRequired)
+		if c.Optional {
+			rep = repetitionOptional
+		}
+		e.i32(3, rep)
+		e.binary(4, c.Name)
+		// both of converted and logical types, for old and new readers.
+		switch c.Type {
+		case String:
+			e.i32(6, convertedUTF8)
+			e.structField(10)
+			e.structField(logicalString)
+			e.structEnd()
+			e.structEnd()
+		case Date:
+			e.i32(6, convertedDate)
+			e.structField(10)
+			e.structField(logicalDate)
+			e.structEnd()
+			e.structEnd()
+		}
+		e.structEnd()
+	}
+	e.i64(3, w.rows)
+	e.listBegin(4, tStruct, len(w.groups))
+	for _, g := range w.groups {
+		e.structBegin()
+		e.listBegin(1, tStruct, len(g.chunks))
+		for i, ch := range g.chunks {
+			c := w.columns[i]
+			e.structBegin()
+			e.i64(2, ch.offset) // file_offset
+			e.structField(3)    // meta_data
+			e.i32(1, c.physicalType())
+			e.listI32(2, encodingPlain, encodingRLE)
+			e.listBinary(3, c.Name)
+			e.i32(4, codecUncompressed)
+			e.i64(5, ch.numValues)
+			e.i64(6, ch.size) // total_uncompressed_size
+			e.i64(7, ch.size) // total_compressed_size
+			e.i64(9, ch.offset)
+			e.structEnd()
+			e.structEnd()
+		}
+		e.i64(2, g.totalSize)
+		e.i64(3, g.rows)
+		e.structEnd()
+	}
+	e.binary(6, "funddb")
+	e.structEnd()
+	return e.buf.Bytes()
+}
+
+// Close writes buffered rows and the footer. It doesn't close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if err := w.flushRowGroup(); err != nil {
+		return err
+	}
+	meta := w.encodeFileMetaData()
+	w.write(meta)
+	w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta))))
+	w.write([]byte("PAR1"))
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
@@ -0,0 +1,444 @@
+package parquet_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/google/go-cmp/cmp"
+	"github.com/koron/funddb/internal/parquet"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// compactDecoder decodes structures in the Thrift compact protocol, into
+// map[int16]any for structs, []any for lists, string for binaries and int64
+// for integers.
+type compactDecoder struct {
+	b []byte
+}
+
+func (d *compactDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *compactDecoder) value(typ byte) any {
+	switch typ {
+	case 5, 6:
+		v, n := binary.Varint(d.b)
+		d.b = d.b[n:]
+		return v
+	case 8:
+		n := d.uvarint()
+		s := string(d.b[:n])
+		d.b = d.b[n:]
+		return s
+	case 9:
+		h := d.b[0]
+		d.b = d.b[1:]
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(d.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = d.value(h & 0x0f)
+		}
+		return list
+	case 12:
+		return d.structure()
+	default:
+		panic(fmt.Sprintf("unsupported type: %d", typ))
+	}
+}
+
+func (d *compactDecoder) structure() map[int16]any {
+	m := map[int16]any{}
+	var id int16
+	for {
+		h := d.b[0]
+		d.b = d.b[1:]
+		if h == 0 {
+			return m
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, n := binary.Varint(d.b)
+			d.b = d.b[n:]
+			id = int16(v)
+		}
+		m[id] = d.value(h & 0x0f)
+	}
+}
+
+// readFile reads all values of columns from a Parquet file.
+func readFile(t *testing.T, b []byte) (map[int16]any, [][]any) {
+	t.Helper()
+	if !bytes.HasPrefix(b, []byte("PAR1")) || !bytes.HasSuffix(b, []byte("PAR1")) {
+		t.Fatal("no magic bytes")
+	}
+	n := binary.LittleEndian.Uint32(b[len(b)-8:])
+	meta := (&compactDecoder{b: b[len(b)-8-int(n) : len(b)-8]}).structure()
+	schema := meta[2].([]any)
+	columns := make([][]any, len(schema)-1)
+	for _, g := range meta[4].([]any) {
+		for i, ch := range g.(map[int16]any)[1].([]any) {
+			cm := ch.(map[int16]any)[3].(map[int16]any)
+			d := &compactDecoder{b: b[cm[9].(int64):]}
+			header := d.structure()
+			page := d.b[:header[3].(int64)]
+			numValues := int(header[5].(map[int16]any)[1].(int64))
+			se := schema[i+1].(map[int16]any)
+			defined := make([]bool, numValues)
+			for j := range defined {
+				defined[j] = true
+			}
+			if se[3].(int64) == 1 {
+				size := binary.LittleEndian.Uint32(page)
+				levels := &compactDecoder{b: page[4 : 4+size]}
+				if h := levels.uvarint(); h&1 != 1 {
+					t.Fatalf("unexpected RLE run: %d", h)
+				}
+				for j := range defined {
+					defined[j] = levels.b[j/8]&(1<<(j%8)) != 0
+				}
+				page = page[4+size:]
+			}
+			for _, ok := range defined {
+				if !ok {
+					columns[i] = append(columns[i], nil)
+					continue
+				}
+				switch se[1].(int64) {
+				case 1:
+					columns[i] = append(columns[i], int32(binary.LittleEndian.Uint32(page)))
+					page = page[4:]
+				case 2:
+					columns[i] = append(columns[i], int64(binary.LittleEndian.Uint64(page)))
+					page = page[8:]
+				case 6:
+					size := binary.LittleEndian.Uint32(page)
+					columns[i] = append(columns[i], string(page[4:4+size]))
+					page = page[4+size:]
+				}
+			}
+			if len(page) != 0 {
+				t.Fatalf("extra bytes in page of column #%d: %d", i, len(page))
+			}
+		}
+	}
+	return meta, columns
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, []parquet.Column{
+		{Name: "id", Type: parquet.String},
+		{Name: "date", Type: parquet.Date},
+		{Name: "value", Type: parquet.Int64},
+		{Name: "net_assets", Type: parquet.Int64, Optional: true},
+	}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want [4][]any
+	for i := range 10 {
+		date := time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		row := []any{fmt.Sprintf("F%d", i%3), date, int64(10000 + i), nil}
+		if i%3 != 0 {
+			row[3] = int64(i) * 1_000_000
+		}
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+		want[0] = append(want[0], row[0])
+		want[1] = append(want[1], int32(19723+i))
+		want[2] = append(want[2], row[2])
+		want[3] = append(want[3], row[3])
+	}
+	if err := w.WriteRow([]any{"X", time.Now(), nil, nil}); err == nil {
+		t.Errorf("nil for a required column should fail")
+	}
+	if err := w.WriteRow([]any{"X", time.Now(), 1, nil}); err == nil {
+		t.Errorf("int for an Int64 column should fail")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, columns := readFile(t, buf.Bytes())
+	if meta[3].(int64) != 10 {
+		t.Errorf("unexpected num_rows: %v", meta[3])
+	}
+	if n := len(meta[4].([]any)); n != 3 {
+		t.Errorf("unexpected number of row groups: %d", n)
+	}
+	for i := range columns {
+		if d := cmp.Diff(want[i], columns[i]); d != "" {
+			t.Errorf("unmatch column #%d: -want +got\n%s", i, d)
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, []parquet.Column{{Name: "id", Type: parquet.String}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	meta, _ := readFile(t, buf.Bytes())
+	if meta[3].(int64) != 0 || len(meta[4].([]any)) != 0 {
+		t.Errorf("unexpected metadata: %v", meta)
+	}
+}
+
+// writeGolden writes a table for the golden file: two row groups, with
+// all types of columns and a nil value.
+func writeGolden(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, []parquet.Column{
+		{Name: "id", Type: parquet.String},
+		{Name: "date", Type: parquet.Date},
+		{Name: "value", Type: parquet.Int64},
+		{Name: "net_assets", Type: parquet.Int64, Optional: true},
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]any{
+		{"F1", time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC), int64(12345), int64(45_678_000_000)},
+		{"F1", time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC), int64(12400), nil},
+		{"F2", time.Date(2024, 6, 24, 0, 0, 0, 0, time.UTC), int64(9800), int64(1_200_000_000)},
+	} {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestWriterGolden compares an output with testdata/golden.parquet, and
+// checks its metadata with field IDs in parquet.thrift of the Parquet
+// format. Run with -update to update the golden file after checking it with
+// other readers, like:
+//
+//	python3 -c 'import pyarrow.parquet as pq; print(pq.read_metadata("testdata/golden.parquet").schema, pq.read_table("testdata/golden.parquet"))'
+func TestWriterGolden(t *testing.T) {
+	name := filepath.Join("testdata", "golden.parquet")
+	b := writeGolden(t)
+	if *update {
+		if err := os.WriteFile(name, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, golden) {
+		t.Fatalf("output doesn't match with %s", name)
+	}
+
+	// schemaElement is a SchemaElement of a column: type (1),
+	// repetition_type (3), name (4), and optional converted_type (6) with
+	// logicalType (10).
+	schemaElement := func(typ, repetition int64, name string, annotation ...int64) map[int16]any {
+		se := map[int16]any{1: typ, 3: repetition, 4: name}
+		if len(annotation) > 0 {
+			se[6] = annotation[0]
+			se[10] = map[int16]any{int16(annotation[1]): map[int16]any{}}
+		}
+		return se
+	}
+	// columnChunk is a ColumnChunk: file_offset (2) and meta_data (3) with
+	// type (1), encodings (2), path_in_schema (3), codec (4), num_values
+	// (5), total_uncompressed_size (6), total_compressed_size (7) and
+	// data_page_offset (9).
+	columnChunk := func(typ int64, name string, offset, numValues, size int64) map[int16]any {
+		return map[int16]any{2: offset, 3: map[int16]any{
+			1: typ, 2: []any{int64(0), int64(3)}, 3: []any{name}, 4: int64(0),
+			5: numValues, 6: size, 7: size, 9: offset,
+		}}
+	}
+	// types are INT32 (1), INT64 (2) and BYTE_ARRAY (6). annotations are
+	// UTF8 (0) with STRING (1), and DATE (6) with DATE (6).
+	want := map[int16]any{
+		1: int64(1), // version
+		2: []any{ // schema
+			map[int16]any{4: "schema", 5: int64(4)},
+			schemaElement(6, 0, "id", 0, 1),
+			schemaElement(1, 0, "date", 6, 6),
+			schemaElement(2, 0, "value"),
+			schemaElement(2, 1, "net_assets"),
+		},
+		3: int64(3), // num_rows
+		4: []any{ // row_groups: columns (1), total_byte_size (2) and num_rows (3)
+			map[int16]any{1: []any{
+				columnChunk(6, "id", 4, 2, 29),
+				columnChunk(1, "date", 33, 2, 25),
+				columnChunk(2, "value", 58, 2, 33),
+				columnChunk(2, "net_assets", 91, 2, 31),
+			}, 2: int64(118), 3: int64(2)},
+			map[int16]any{1: []any{
+				columnChunk(6, "id", 122, 1, 23),
+				columnChunk(1, "date", 145, 1, 21),
+				columnChunk(2, "value", 166, 1, 25),
+				columnChunk(2, "net_assets", 191, 1, 31),
+			}, 2: int64(100), 3: int64(1)},
+		},
+		6: "funddb", // created_by
+	}
+	meta, columns := readFile(t, golden)
+	if d := cmp.Diff(want, meta); d != "" {
+		t.Errorf("unmatch metadata: -want +got\n%s", d)
+	}
+	// column chunks follow the magic bytes one after another, and the footer
+	// follows them.
+	offset := int64(4)
+	for i, g := range meta[4].([]any) {
+		for j, ch := range g.(map[int16]any)[1].([]any) {
+			cm := ch.(map[int16]any)[3].(map[int16]any)
+			if cm[9].(int64) != offset {
+				t.Errorf("unexpected offset of chunk #%d in row group #%d: want=%d got=%d", j, i, offset, cm[9])
+			}
+			offset += cm[7].(int64)
+		}
+	}
+	footer := int64(len(golden)) - 8 - int64(binary.LittleEndian.Uint32(golden[len(golden)-8:]))
+	if offset != footer {
+		t.Errorf("unexpected offset of the footer: want=%d got=%d", footer, offset)
+	}
+	wantColumns := [][]any{
+		{"F1", "F1", "F2"},
+		{int32(19898), int32(19899), int32(19898)},
+		{int64(12345), int64(12400), int64(9800)},
+		{int64(45_678_000_000), nil, int64(1_200_000_000)},
+	}
+	if d := cmp.Diff(wantColumns, columns); d != "" {
+		t.Errorf("unmatch columns: -want +got\n%s", d)
+	}
+}
+
+// readArrow reads a Parquet file with the reader of Apache Arrow, and returns
+// the file reader and the table.
+func readArrow(t *testing.T, b []byte) (*file.Reader, arrow.Table) {
+	t.Helper()
+	r, err := file.NewParquetReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	fr, err := pqarrow.NewFileReader(r, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := fr.ReadTable(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tbl.Release)
+	return r, tbl
+}
+
+// columnValues returns values of a column in a table, as strings like
+// pyarrow prints.
+func columnValues(tbl arrow.Table, i int) []string {
+	var values []string
+	for _, chunk := range tbl.Column(i).Data().Chunks() {
+		for j := range chunk.Len() {
+			values = append(values, chunk.ValueStr(j))
+		}
+	}
+	return values
+}
+
+// TestWriterArrow checks an output is readable by Apache Arrow, with logical
+// types, row groups and nil values.
+func TestWriterArrow(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, []parquet.Column{
+		{Name: "id", Type: parquet.String},
+		{Name: "date", Type: parquet.Date},
+		{Name: "value", Type: parquet.Int64},
+		{Name: "net_assets", Type: parquet.Int64, Optional: true},
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want [4][]string
+	for i := range 5 {
+		date := time.Date(2024, 6, 24+i, 0, 0, 0, 0, time.UTC)
+		row := []any{fmt.Sprintf("基準%d", i), date, int64(10000 + i), nil}
+		want[3] = append(want[3], "(null)")
+		if i%2 == 0 {
+			row[3] = int64(i) * 1_000_000
+			want[3][i] = fmt.Sprint(row[3])
+		}
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+		want[0] = append(want[0], row[0].(string))
+		want[1] = append(want[1], date.Format(time.DateOnly))
+		want[2] = append(want[2], fmt.Sprint(row[2]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, tbl := readArrow(t, buf.Bytes())
+	if n := r.NumRowGroups(); n != 3 {
+		t.Errorf("unexpected number of row groups: %d", n)
+	}
+	schema := r.MetaData().Schema
+	for i, want := range []string{"String", "Date", "None", "None"} {
+		if got := schema.Column(i).LogicalType().String(); got != want {
+			t.Errorf("unexpected logical type of %s: want=%s got=%s", schema.Column(i).Name(), want, got)
+		}
+	}
+	wantSchema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.BinaryTypes.String},
+		{Name: "date", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "net_assets", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	// ignore field IDs in metadata of fields.
+	if got := arrow.NewSchema(unmetadata(tbl.Schema().Fields()), nil); !got.Equal(wantSchema) {
+		t.Errorf("unmatch schema:\nwant=%s\ngot=%s", wantSchema, got)
+	}
+	if tbl.NumRows() != 5 {
+		t.Errorf("unexpected number of rows: %d", tbl.NumRows())
+	}
+	for i := range want {
+		if d := cmp.Diff(want[i], columnValues(tbl, i)); d != "" {
+			t.Errorf("unmatch column #%d: -want +got\n%s", i, d)
+		}
+	}
+}
+
+// unmetadata removes metadata from fields.
+func unmetadata(fields []arrow.Field) []arrow.Field {
+	for i := range fields {
+		fields[i].Metadata = arrow.Metadata{}
+	}
+	return fields
+}
@@ -0,0 +1,99 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Types of fields in the Thrift compact protocol.
+const (
+	tI32    = 5
+	tI64    = 6
+	tBinary = 8
+	tList   = 9
+	tStruct = 12
+)
+
+// compactEncoder encodes structures of the Parquet metadata in the Thrift
+// compact protocol. Only a subset which the metadata requires is
+// implemented.
+type compactEncoder struct {
+	buf    bytes.Buffer
+	lastID []int16
+}
+
+func (e *compactEncoder) uvarint(v uint64) {
+	e.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (e *compactEncoder) varint(v int64) {
+	e.buf.Write(binary.AppendVarint(nil, v))
+}
+
+func (e *compactEncoder) structBegin() {
+	e.lastID = append(e.lastID, 0)
+}
+
+func (e *compactEncoder) structEnd() {
+	e.buf.WriteByte(0) // STOP
+	e.lastID = e.lastID[:len(e.lastID)-1]
+}
+
+func (e *compactEncoder) field(id int16, typ byte) {
+	last := &e.lastID[len(e.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		e.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		e.buf.WriteByte(typ)
+		e.varint(int64(id))
+	}
+	*last = id
+}
+
+func (e *compactEncoder) i32(id int16, v int32) {
+	e.field(id, tI32)
+	e.varint(int64(v))
+}
+
+func (e *compactEncoder) i64(id int16, v int64) {
+	e.field(id, tI64)
+	e.varint(v)
+}
+
+func (e *compactEncoder) binary(id int16, s string) {
+	e.field(id, tBinary)
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *compactEncoder) listBegin(id int16, elemType byte, n int) {
+	e.field(id, tList)
+	if n < 15 {
+		e.buf.WriteByte(byte(n)<<4 | elemType)
+		return
+	}
+	e.buf.WriteByte(0xf0 | elemType)
+	e.uvarint(uint64(n))
+}
+
+func (e *compactEncoder) structField(id int16) {
+	e.field(id, tStruct)
+	e.structBegin()
+}
+
+// listI32 writes elements of list<i32>.
+func (e *compactEncoder) listI32(id int16, values ...int32) {
+	e.listBegin(id, tI32, len(values))
+	for _, v := range values {
+		e.varint(int64(v))
+	}
+}
+
+// listBinary writes elements of list<binary>.
+func (e *compactEncoder) listBinary(id int16, values ...string) {
+	e.listBegin(id, tBinary, len(values))
+	for _, s := range values {
+		e.uvarint(uint64(len(s)))
+		e.buf.WriteString(s)
+	}
+}
@@ -0,0 +1,88 @@
+// Package portfolio calculates holdings of funds from transactions.
+package portfolio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/koron/funddb/internal/dataobj"
+)
+
+// UnitsPerQuote is the number of units which a NAV is quoted for. Japanese
+// NAVs are quoted per 10,000 units.
+const UnitsPerQuote = 10_000
+
+// Holding is a position of a fund in an account.
+type Holding struct {
+	AccountID string
+	FundID    string
+	Units     int64 // Units (口)
+	Cost      int64 // Cost basis in yen, by the average cost method
+	Realized  int64 // Realized gain in yen by sells
+}
+
+// Apply applies a transaction to the holding.
+func (h *Holding) Apply(t dataobj.Transaction) error {
+	switch t.Type {
+	case dataobj.TxnBuy, dataobj.TxnReinvest:
+		h.Units += t.Units
+		h.Cost += t.Amount
+	case dataobj.TxnSell:
+		if t.Units > h.Units {
+			return fmt.Errorf("transaction #%d sells %d units, more than held %d units", t.ID, t.Units, h.Units)
+		}
+		removed := int64(math.Round(float64(h.Cost) * float64(t.Units) / float64(h.Units)))
+		h.Units -= t.Units
+		h.Cost -= removed
+		h.Realized += t.Amount - removed
+	case dataobj.TxnFee:
+		// a fee may be paid by redeeming units.
+		if t.Units > h.Units {
+			return fmt.Errorf("transaction #%d redeems %d units for fee, more than held %d units", t.ID, t.Units, h.Units)
+		}
+		h.Units -= t.Units
+		h.Cost += t.Amount
+	default:
+		return fmt.Errorf("transaction #%d has unknown type %q", t.ID, t.Type)
+	}
+	return nil
+}
+
+// Value returns a market value in yen of the holding by a NAV.
+func (h Holding) Value(nav int64) int64 {
+	return int64(math.Round(float64(h.Units) * float64(nav) / UnitsPerQuote))
+}
+
+// Holdings calculates holdings from transactions, which should be sorted by
+// date. Transactions after date are ignored when date is not zero. Returned
+// holdings are sorted by account and fund IDs.
+func Holdings(txns []dataobj.Transaction, date dataobj.Date) ([]*Holding, error) {
+	type key struct{ account, fund string }
+	m := map[key]*Holding{}
+	for _, t := range txns {
+		if !date.IsZero() && t.Date.String() > date.String() {
+			continue
+		}
+		k := key{t.AccountID, t.FundID}
+		h, ok := m[k]
+		if !ok {
+			h = &Holding{AccountID: t.AccountID, FundID: t.FundID}
+			m[k] = h
+		}
+		if err := h.Apply(t); err != nil {
+			return nil, err
+		}
+	}
+	list := make([]*Holding, 0, len(m))
+	for _, h := range m {
+		list = append(list, h)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].AccountID != list[j].AccountID {
+			return list[i].AccountID < list[j].AccountID
+		}
+		return list[i].FundID < list[j].FundID
+	})
+	return list, nil
+}
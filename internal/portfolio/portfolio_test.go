@@ -0,0 +1,50 @@
+package portfolio_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/portfolio"
+)
+
+func TestHoldings(t *testing.T) {
+	txns := []dataobj.Transaction{
+		{ID: 1, AccountID: "a", FundID: "X", Date: dataobj.NewDate(2024, 1, 5), Type: dataobj.TxnBuy, Units: 10000, Amount: 10000},
+		{ID: 2, AccountID: "a", FundID: "X", Date: dataobj.NewDate(2024, 2, 5), Type: dataobj.TxnBuy, Units: 5000, Amount: 6000},
+		{ID: 3, AccountID: "b", FundID: "X", Date: dataobj.NewDate(2024, 2, 5), Type: dataobj.TxnBuy, Units: 8000, Amount: 9600},
+		{ID: 4, AccountID: "a", FundID: "X", Date: dataobj.NewDate(2024, 3, 5), Type: dataobj.TxnSell, Units: 3000, Amount: 4000},
+		{ID: 5, AccountID: "a", FundID: "X", Date: dataobj.NewDate(2024, 4, 5), Type: dataobj.TxnReinvest, Units: 100, Amount: 130},
+		{ID: 6, AccountID: "a", FundID: "X", Date: dataobj.NewDate(2024, 5, 5), Type: dataobj.TxnFee, Units: 0, Amount: 50},
+	}
+	got, err := portfolio.Holdings(txns, dataobj.Date{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*portfolio.Holding{
+		// cost 16000 for 15000 units, sell 3000 units removes 3200.
+		{AccountID: "a", FundID: "X", Units: 12100, Cost: 16000 - 3200 + 130 + 50, Realized: 800},
+		{AccountID: "b", FundID: "X", Units: 8000, Cost: 9600},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unmatch holdings: -want +got\n%s", d)
+	}
+	if v := got[0].Value(13000); v != 15730 {
+		t.Errorf("unexpected value: %d", v)
+	}
+
+	got, err = portfolio.Holdings(txns, dataobj.NewDate(2024, 2, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Units != 15000 || got[0].Cost != 16000 {
+		t.Errorf("unexpected holdings on 2024-02-05: %+v", got[0])
+	}
+
+	_, err = portfolio.Holdings([]dataobj.Transaction{
+		{ID: 1, AccountID: "a", FundID: "X", Type: dataobj.TxnSell, Units: 1, Amount: 1},
+	}, dataobj.Date{})
+	if err == nil {
+		t.Error("no errors for overselling")
+	}
+}
@@ -0,0 +1,332 @@
+// Package pricecsv reads histories of prices from CSV files, which asset
+// managers publish.
+package pricecsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/koron/funddb/internal/dataobj"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/width"
+)
+
+// Column specifies a column by 1-based position, or by a name in a header
+// row when Name is not empty.
+type Column struct {
+	Pos  int
+	Name string
+}
+
+// IsZero reports whether the column is not specified.
+func (c Column) IsZero() bool {
+	return c.Pos == 0 && c.Name == ""
+}
+
+func (c Column) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return strconv.Itoa(c.Pos)
+}
+
+// Layout is a mapping of columns in CSV files.
+type Layout struct {
+	Name string
+	Desc string
+
+	Date      Column
+	Price     Column
+	NetAssets Column // optional
+
+	// NetAssetsUnit is yen for a unit of net assets, like 1,000,000 for
+	// "百万円". It is guessed by a suffix of values when zero.
+	NetAssetsUnit int64
+}
+
+// Layouts are built-in layouts.
+var Layouts = []Layout{
+	{
+		Name:  "date-price",
+		Desc:  "日付, 基準価額",
+		Date:  Column{Pos: 1},
+		Price: Column{Pos: 2},
+	},
+	{
+		Name:      "date-price-assets",
+		Desc:      "日付, 基準価額, 純資産総額(円)",
+		Date:      Column{Pos: 1},
+		Price:     Column{Pos: 2},
+		NetAssets: Column{Pos: 3},
+	},
+	{
+		Name:          "date-price-assets-m",
+		Desc:          "日付, 基準価額, 純資産総額(百万円)",
+		Date:          Column{Pos: 1},
+		Price:         Column{Pos: 2},
+		NetAssets:     Column{Pos: 3},
+		NetAssetsUnit: 1_000_000,
+	},
+	{
+		Name:          "date-price-dist-assets-m",
+		Desc:          "日付, 基準価額, 分配金, 純資産総額(百万円)",
+		Date:          Column{Pos: 1},
+		Price:         Column{Pos: 2},
+		NetAssets:     Column{Pos: 4},
+		NetAssetsUnit: 1_000_000,
+	},
+}
+
+// LookupLayout finds a built-in layout by name.
+func LookupLayout(name string) (Layout, bool) {
+	for _, l := range Layouts {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return Layout{}, false
+}
+
+// Units are names of units for net assets.
+var Units = map[string]int64{
+	"円":   1,
+	"千円":  1_000,
+	"百万円": 1_000_000,
+	"億円":  100_000_000,
+}
+
+// ParseColumns parses a custom column mapping like
+// "date=1,price=2,net_assets=4" or "date=基準日,price=基準価額". Positions
+// are 1-based, and other values are names in a header row.
+func ParseColumns(s string) (Layout, error) {
+	l := Layout{Name: "custom"}
+	for _, item := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok {
+			return Layout{}, fmt.Errorf("invalid column mapping %q, require KEY=COLUMN", item)
+		}
+		v = strings.TrimSpace(v)
+		var c Column
+		if n, err := strconv.Atoi(v); err == nil {
+			if n <= 0 {
+				return Layout{}, fmt.Errorf("column position should be positive: %d", n)
+			}
+			c.Pos = n
+		} else {
+			c.Name = v
+		}
+		switch strings.TrimSpace(k) {
+		case "date":
+			l.Date = c
+		case "price":
+			l.Price = c
+		case "net_assets":
+			l.NetAssets = c
+		default:
+			return Layout{}, fmt.Errorf("unknown column key %q, available keys are: date, price, net_assets", k)
+		}
+	}
+	if l.Date.IsZero() || l.Price.IsZero() {
+		return Layout{}, errors.New("date and price columns are required")
+	}
+	return l, nil
+}
+
+// Decode converts content of a file to UTF-8. It removes a BOM, and decodes
+// Shift_JIS when the content is not valid UTF-8.
+func Decode(b []byte) ([]byte, error) {
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(b) {
+		return b, nil
+	}
+	return japanese.ShiftJIS.NewDecoder().Bytes(b)
+}
+
+var eras = []struct {
+	names []string
+	start int // the first year of the era, in A.D.
+}{
+	{[]string{"令和", "R"}, 2019},
+	{[]string{"平成", "H"}, 1989},
+}
+
+var (
+	dateRx       = regexp.MustCompile(`^(\d{1,4})[年/.\-](\d{1,2})[月/.\-](\d{1,2})日?$`)
+	digitsDateRx = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})$`)
+)
+
+// ParseDate parses dates in Japanese formats: "2024年1月5日", "2024/1/5",
+// "2024-01-05", "2024.1.5", "20240105", "令和6年1月5日", "R6.1.5" and
+// so on.
+func ParseDate(s string) (dataobj.Date, error) {
+	s = strings.ReplaceAll(width.Narrow.String(strings.TrimSpace(s)), " ", "")
+	offset := 0
+	for _, era := range eras {
+		for _, name := range era.names {
+			if rest, ok := strings.CutPrefix(s, name); ok {
+				s = strings.Replace(rest, "元", "1", 1)
+				offset = era.start - 1
+			}
+		}
+	}
+	sub := dateRx.FindStringSubmatch(s)
+	if sub == nil && offset == 0 {
+		sub = digitsDateRx.FindStringSubmatch(s)
+	}
+	if sub == nil {
+		return dataobj.Date{}, fmt.Errorf("invalid date %q", s)
+	}
+	y, _ := strconv.Atoi(sub[1])
+	m, _ := strconv.Atoi(sub[2])
+	d, _ := strconv.Atoi(sub[3])
+	y += offset
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Year() != y || int(t.Month()) != m || t.Day() != d || y < 1900 {
+		return dataobj.Date{}, fmt.Errorf("invalid date %q", s)
+	}
+	return dataobj.DateFromTime(t), nil
+}
+
+// ParseNumber parses a number with comma separators and an optional unit
+// like "12,345円", and multiplies it by unit. When unit is zero, it is given
+// by the suffix, or 1 without suffixes. A suffix which conflicts with unit
+// fails. A fraction is rounded.
+func ParseNumber(s string, unit int64) (int64, error) {
+	s = width.Narrow.String(strings.TrimSpace(s))
+	// the longest suffix first, as "円" is a suffix of others.
+	for _, name := range []string{"百万円", "千円", "億円", "円"} {
+		if t, ok := strings.CutSuffix(s, name); ok {
+			if unit != 0 && unit != Units[name] {
+				return 0, fmt.Errorf("unit of %q conflicts with %d yen", s, unit)
+			}
+			s, unit = t, Units[name]
+			break
+		}
+	}
+	if unit == 0 {
+		unit = 1
+	}
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return int64(math.Round(v * float64(unit))), nil
+}
+
+// Result is prices read from a file.
+type Result struct {
+	Prices []dataobj.Price
+
+	// Skipped are line numbers of rows which have no dates, like headers
+	// and notes.
+	Skipped []int
+}
+
+// Read reads prices of a fund from CSV with a layout. Rows whose date
+// columns are not dates are skipped. Errors in other columns fail with
+// line numbers.
+func Read(r io.Reader, id string, l Layout) (*Result, error) {
+	unit := l.NetAssetsUnit
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+
+	index := map[Column]int{}
+	cols := []Column{l.Date, l.Price}
+	if !l.NetAssets.IsZero() {
+		cols = append(cols, l.NetAssets)
+	}
+	named := false
+	for _, c := range cols {
+		if c.Name != "" {
+			named = true
+		} else {
+			index[c] = c.Pos - 1
+		}
+	}
+
+	res := &Result{}
+	for {
+		rec, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if named && findHeader(rec, cols, index) {
+			named = false
+			res.Skipped = append(res.Skipped, line)
+			continue
+		}
+		get := func(c Column) (string, bool) {
+			i, ok := index[c]
+			if !ok || i >= len(rec) {
+				return "", false
+			}
+			return rec[i], true
+		}
+		s, ok := get(l.Date)
+		if !ok || named {
+			res.Skipped = append(res.Skipped, line)
+			continue
+		}
+		date, err := ParseDate(s)
+		if err != nil {
+			res.Skipped = append(res.Skipped, line)
+			continue
+		}
+		p := dataobj.Price{ID: id, Date: date}
+		s, _ = get(l.Price)
+		p.Value, err = ParseNumber(s, 1)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: price: %w", line, err)
+		}
+		if !l.NetAssets.IsZero() {
+			if s, ok := get(l.NetAssets); ok && strings.TrimSpace(s) != "" && strings.TrimSpace(s) != "-" {
+				p.NetAssets, err = ParseNumber(s, unit)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: net assets: %w", line, err)
+				}
+			}
+		}
+		res.Prices = append(res.Prices, p)
+	}
+	return res, nil
+}
+
+// findHeader finds positions of named columns in a row, and reports
+// whether all of them are found.
+func findHeader(rec []string, cols []Column, index map[Column]int) bool {
+	found := map[Column]int{}
+	for _, c := range cols {
+		if c.Name == "" {
+			continue
+		}
+		for i, s := range rec {
+			if strings.TrimSpace(width.Narrow.String(s)) == width.Narrow.String(c.Name) {
+				found[c] = i
+				break
+			}
+		}
+		if _, ok := found[c]; !ok {
+			return false
+		}
+	}
+	for c, i := range found {
+		index[c] = i
+	}
+	return true
+}
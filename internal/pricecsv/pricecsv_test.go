@@ -0,0 +1,187 @@
+package pricecsv_test
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/pricecsv"
+)
+
+func TestParseDate(t *testing.T) {
+	want := dataobj.NewDate(2024, 1, 5)
+	for _, s := range []string{
+		"2024年1月5日",
+		"2024年01月05日",
+		"2024/1/5",
+		"2024-01-05",
+		"2024.1.5",
+		"20240105",
+		"令和6年1月5日",
+		"R6.1.5",
+		"２０２４／０１／０５",
+		" 2024/01/05 ",
+	} {
+		got, err := pricecsv.ParseDate(s)
+		if err != nil {
+			t.Errorf("failed to parse %q: %s", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("unmatch date for %q: got=%s", s, got)
+		}
+	}
+	if got, err := pricecsv.ParseDate("令和元年5月1日"); err != nil || got != dataobj.NewDate(2019, 5, 1) {
+		t.Errorf("unexpected first year of Reiwa: %s %v", got, err)
+	}
+	for _, s := range []string{"", "基準日", "2024/2/30", "2024/13/1", "12345678", "1/5"} {
+		if _, err := pricecsv.ParseDate(s); err == nil {
+			t.Errorf("no errors for %q", s)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		unit int64
+		want int64
+	}{
+		{"12,345", 1, 12345},
+		{"12,345円", 1, 12345},
+		{"１２，３４５", 1, 12345},
+		{"1,234.5", 1_000_000, 1_234_500_000},
+		{"1,234.5百万円", 1_000_000, 1_234_500_000},
+		{"-0.4", 1, 0},
+		{"1,234百万円", 0, 1_234_000_000},
+		{"1,234.5千円", 0, 1_234_500},
+		{"2.5億円", 0, 250_000_000},
+		{"12,345円", 0, 12345},
+		{"12,345", 0, 12345},
+		{"1,234百万円", 1_000_000, 1_234_000_000},
+	} {
+		got, err := pricecsv.ParseNumber(tc.s, tc.unit)
+		if err != nil {
+			t.Errorf("failed to parse %q: %s", tc.s, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("unmatch number for %q: want=%d got=%d", tc.s, tc.want, got)
+		}
+	}
+	if _, err := pricecsv.ParseNumber("-", 1); err == nil {
+		t.Errorf("no errors for \"-\"")
+	}
+	for _, tc := range []struct {
+		s    string
+		unit int64
+	}{
+		{"1,234百万円", 1},
+		{"1,234千円", 1_000_000},
+		{"12,345円", 1_000_000},
+	} {
+		if _, err := pricecsv.ParseNumber(tc.s, tc.unit); err == nil || !strings.Contains(err.Error(), "conflicts") {
+			t.Errorf("unexpected error for %q with %d: %v", tc.s, tc.unit, err)
+		}
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	l, err := pricecsv.ParseColumns("date=基準日, price=2,net_assets=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Date != (pricecsv.Column{Name: "基準日"}) || l.Price != (pricecsv.Column{Pos: 2}) || l.NetAssets != (pricecsv.Column{Pos: 4}) {
+		t.Errorf("unexpected layout: %+v", l)
+	}
+	for _, s := range []string{"date=1", "date=1,price=0", "date=1,price=2,nav=3", "date"} {
+		if _, err := pricecsv.ParseColumns(s); err == nil {
+			t.Errorf("no errors for %q", s)
+		}
+	}
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = pricecsv.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestReadShiftJIS(t *testing.T) {
+	b := readTestdata(t, "testdata/sjis.csv")
+	l, _ := pricecsv.LookupLayout("date-price-dist-assets-m")
+	res, err := pricecsv.Read(bytes.NewReader(b), "X", l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dataobj.Price{
+		{ID: "X", Date: dataobj.NewDate(2024, 1, 4), Value: 23456, NetAssets: 12_345_600_000},
+		{ID: "X", Date: dataobj.NewDate(2024, 1, 5), Value: 23512, NetAssets: 12_400_000_000},
+		{ID: "X", Date: dataobj.NewDate(2024, 1, 9), Value: 23600},
+	}
+	if d := cmp.Diff(want, res.Prices); d != "" {
+		t.Errorf("unmatch prices: -want +got\n%s", d)
+	}
+	if !slices.Equal(res.Skipped, []int{1, 2, 6}) {
+		t.Errorf("unexpected skipped lines: %v", res.Skipped)
+	}
+}
+
+func TestReadNamedColumns(t *testing.T) {
+	b := readTestdata(t, "testdata/sjis.csv")
+	l, err := pricecsv.ParseColumns("date=基準日,price=基準価額(円)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := pricecsv.Read(bytes.NewReader(b), "X", l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Prices) != 3 || res.Prices[2].Value != 23600 || res.Prices[0].NetAssets != 0 {
+		t.Errorf("unexpected prices: %+v", res.Prices)
+	}
+}
+
+func TestReadSuffixedNumbers(t *testing.T) {
+	in := "日付,基準価額,純資産総額\n2024/01/04,\"12,000円\",\"1,234百万円\"\n2024/01/05,12100,\"5,678千円\"\n2024/01/09,12200,123456789\n"
+	l, _ := pricecsv.LookupLayout("date-price-assets")
+	res, err := pricecsv.Read(strings.NewReader(in), "X", l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dataobj.Price{
+		{ID: "X", Date: dataobj.NewDate(2024, 1, 4), Value: 12000, NetAssets: 1_234_000_000},
+		{ID: "X", Date: dataobj.NewDate(2024, 1, 5), Value: 12100, NetAssets: 5_678_000},
+		{ID: "X", Date: dataobj.NewDate(2024, 1, 9), Value: 12200, NetAssets: 123_456_789},
+	}
+	if d := cmp.Diff(want, res.Prices); d != "" {
+		t.Errorf("unmatch prices: -want +got\n%s", d)
+	}
+
+	// a suffix which conflicts with the unit of a layout.
+	l, _ = pricecsv.LookupLayout("date-price-assets-m")
+	_, err = pricecsv.Read(strings.NewReader(in), "X", l)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3: net assets: ") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	in := "日付,基準価額\n2024/01/04,12000\n2024/01/05,N/A\n"
+	l, _ := pricecsv.LookupLayout("date-price")
+	_, err := pricecsv.Read(strings.NewReader(in), "X", l)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3: ") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+// Package server provides HTTP API to query funds and prices.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/fetcher"
+	"github.com/koron/funddb/internal/output"
+	"xorm.io/xorm"
+)
+
+// Server serves funds and prices in a DB over HTTP.
+type Server struct {
+	ORM *xorm.Engine
+
+	// AllowWrite enables routes which modify the DB, like POST /fetch.
+	// Those routes respond 403 Forbidden when false.
+	AllowWrite bool
+
+	// Fetcher is used by POST /fetch to fetch latest prices.
+	Fetcher *fetcher.Fetcher
+
+	// Logger logs requests. log.Default() is used when nil.
+	Logger *log.Logger
+
+	mu       sync.Mutex
+	fetching bool
+}
+
+// Handler returns a handler which serves all routes with request logging.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /funds", s.listFunds)
+	mux.HandleFunc("GET /funds/{id}", s.getFund)
+	mux.HandleFunc("GET /funds/{id}/prices", s.listPrices)
+	mux.HandleFunc("POST /fetch", s.fetch)
+	return s.logRequests(mux)
+}
+
+// statusRecorder records a status code and size of a response for logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+func (s *Server) logger() *log.Logger {
+	if s.Logger == nil {
+		return log.Default()
+	}
+	return s.Logger
+}
+
+func (s *Server) logRequests(h http.Handler) http.Handler {
+	logger := s.logger()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r)
+		logger.Printf("%s %s %s %d %d %s", r.RemoteAddr, r.Method, r.URL.RequestURI(), rec.status, rec.size, time.Since(start))
+	})
+}
+
+// errorResponse is a body of error responses.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: msg})
+}
+
+func (s *Server) internalError(w http.ResponseWriter, err error) {
+	s.logger().Printf("internal error: %v", err)
+	writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+// responseFormat determines a format of a response, by "format" query
+// parameter or Accept header. JSON is used by default.
+func responseFormat(r *http.Request) (output.Format, error) {
+	if s := r.URL.Query().Get("format"); s != "" {
+		switch f := output.Format(s); f {
+		case output.JSON, output.CSV:
+			return f, nil
+		}
+		return "", errors.New("unsupported format: " + s)
+	}
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case "text/csv":
+			return output.CSV, nil
+		case "application/json":
+			return output.JSON, nil
+		}
+	}
+	return output.JSON, nil
+}
+
+// writeRows writes rows in a format negotiated with a request. When single
+// is true, a JSON response is an object instead of an array.
+func (s *Server) writeRows(w http.ResponseWriter, r *http.Request, columns []string, rows [][]any, single bool) {
+	f, err := responseFormat(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	contentType := "application/json"
+	if f == output.CSV {
+		contentType = "text/csv; charset=utf-8"
+	} else if single {
+		f = output.JSONL
+	}
+	w.Header().Set("Content-Type", contentType)
+	ow, err := output.NewWriter(w, f)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if err := ow.WriteHeader(columns); err != nil {
+		s.logger().Printf("failed to write response: %v", err)
+		return
+	}
+	for _, row := range rows {
+		if err := ow.WriteRow(row); err != nil {
+			s.logger().Printf("failed to write response: %v", err)
+			return
+		}
+	}
+	if err := ow.Flush(); err != nil {
+		s.logger().Printf("failed to write response: %v", err)
+	}
+}
+
+var fundColumns = []string{"id", "name", "url", "fetch_id"}
+
+func fundRow(f dataobj.Fund) []any {
+	return []any{f.ID, f.Name, f.URL, f.FetchID}
+}
+
+func (s *Server) listFunds(w http.ResponseWriter, r *http.Request) {
+	var funds []dataobj.Fund
+	if err := s.ORM.OrderBy("id").Find(&funds); err != nil {
+		s.internalError(w, err)
+		return
+	}
+	rows := make([][]any, len(funds))
+	for i, f := range funds {
+		rows[i] = fundRow(f)
+	}
+	s.writeRows(w, r, fundColumns, rows, false)
+}
+
+// findFund gets a fund specified by a path, or responds 404 Not Found.
+func (s *Server) findFund(w http.ResponseWriter, r *http.Request) (*dataobj.Fund, bool) {
+	var fund dataobj.Fund
+	ok, err := s.ORM.Where("id = ?", r.PathValue("id")).Get(&fund)
+	if err != nil {
+		s.internalError(w, err)
+		return nil, false
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "fund not found: "+r.PathValue("id"))
+		return nil, false
+	}
+	return &fund, true
+}
+
+func (s *Server) getFund(w http.ResponseWriter, r *http.Request) {
+	fund, ok := s.findFund(w, r)
+	if !ok {
+		return
+	}
+	s.writeRows(w, r, fundColumns, [][]any{fundRow(*fund)}, true)
+}
+
+var priceColumns = []string{"id", "date", "value", "net_assets"}
+
+func (s *Server) listPrices(w http.ResponseWriter, r *http.Request) {
+	var q dataobj.PriceQuery
+	for name, d := range map[string]*dataobj.Date{"from": &q.From, "to": &q.To} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		if err := d.Set(v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid "+name+": "+err.Error())
+			return
+		}
+	}
+	fund, ok := s.findFund(w, r)
+	if !ok {
+		return
+	}
+	prices, _, err := q.Find(s.ORM, fund.ID)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	rows := make([][]any, len(prices))
+	for i, p := range prices {
+		var netAssets any
+		if p.NetAssets > 0 {
+			netAssets = p.NetAssets
+		}
+		rows[i] = []any{p.ID, p.Date, p.Value, netAssets}
+	}
+	s.writeRows(w, r, priceColumns, rows, false)
+}
+
+// fetchResponse is a body of a response of POST /fetch.
+type fetchResponse struct {
+	RunID  int64         `json:"run_id"`
+	Total  int           `json:"total"`
+	Failed int           `json:"failed"`
+	Errors []fetchFailed `json:"errors"`
+}
+
+type fetchFailed struct {
+	FundID  string `json:"fund_id"`
+	FetchID string `json:"fetch_id"`
+	Error   string `json:"error"`
+}
+
+// fetch fetches latest prices of all funds, or funds specified by "id"
+// query parameters, like "price fetchlatest" command.
+func (s *Server) fetch(w http.ResponseWriter, r *http.Request) {
+	if !s.AllowWrite {
+		writeError(w, http.StatusForbidden, "write operations are not allowed")
+		return
+	}
+	s.mu.Lock()
+	if s.fetching {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "another fetch is running")
+		return
+	}
+	s.fetching = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.fetching = false
+		s.mu.Unlock()
+	}()
+
+	targets, err := fetcher.LoadTargets(s.ORM, r.URL.Query()["id"])
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	f := s.Fetcher
+	if f == nil {
+		f = &fetcher.Fetcher{}
+	}
+	// fetching continues even when a client disconnects, to store results.
+	startedAt := time.Now()
+	results := f.FetchAll(context.WithoutCancel(r.Context()), targets)
+	run, err := fetcher.Store(s.ORM, startedAt, results)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	resp := fetchResponse{
+		RunID:  run.ID,
+		Total:  run.Total,
+		Failed: run.Failed,
+		Errors: []fetchFailed{},
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			resp.Errors = append(resp.Errors, fetchFailed{FundID: r.FundID, FetchID: r.FetchID, Error: r.Err.Error()})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,195 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/fetcher"
+	"github.com/koron/funddb/internal/fundprice"
+	"github.com/koron/funddb/internal/server"
+)
+
+type testPrice struct {
+	date  time.Time
+	value int64
+}
+
+func (p testPrice) Date() time.Time  { return p.date }
+func (p testPrice) Price() int64     { return p.value }
+func (p testPrice) NetAssets() int64 { return 0 }
+
+func newTestServer(t *testing.T, allowWrite bool) (*server.Server, *httptest.Server) {
+	t.Helper()
+	engine, err := dataobj.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []dataobj.Fund{
+		{ID: "AAA", Name: "Fund A", URL: "https://example.com/a", FetchID: "test:a"},
+		{ID: "BBB", Name: "Fund B", URL: "https://example.com/b", FetchID: "test:b"},
+	} {
+		if _, err := engine.Insert(&f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, v := range []int64{10000, 10100, 10050} {
+		p := dataobj.Price{ID: "AAA", Date: dataobj.NewDate(2024, 1, 4+i), Value: v}
+		if _, err := engine.Insert(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &server.Server{
+		ORM:        engine,
+		AllowWrite: allowWrite,
+		Logger:     log.New(io.Discard, "", 0),
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func doRequest(t *testing.T, method, url string, header http.Header) (int, http.Header, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header, string(b)
+}
+
+func TestRoutes(t *testing.T) {
+	_, ts := newTestServer(t, false)
+	for _, tc := range []struct {
+		method string
+		path   string
+		accept string
+		status int
+		ctype  string
+		body   string
+	}{
+		{"GET", "/funds", "", 200, "application/json",
+			`[
+{"id":"AAA","name":"Fund A","url":"https://example.com/a","fetch_id":"test:a"},
+{"id":"BBB","name":"Fund B","url":"https://example.com/b","fetch_id":"test:b"}
+]
+`},
+		{"GET", "/funds?format=csv", "", 200, "text/csv; charset=utf-8",
+			"id,name,url,fetch_id\nAAA,Fund A,https://example.com/a,test:a\nBBB,Fund B,https://example.com/b,test:b\n"},
+		{"GET", "/funds/BBB", "", 200, "application/json",
+			`{"id":"BBB","name":"Fund B","url":"https://example.com/b","fetch_id":"test:b"}` + "\n"},
+		{"GET", "/funds/AAA/prices?from=2024-01-05", "", 200, "application/json",
+			`[
+{"id":"AAA","date":"2024-01-05","value":10100,"net_assets":null},
+{"id":"AAA","date":"2024-01-06","value":10050,"net_assets":null}
+]
+`},
+		{"GET", "/funds/AAA/prices?to=2024-01-04", "text/csv", 200, "text/csv; charset=utf-8",
+			"id,date,value,net_assets\nAAA,2024-01-04,10000,\n"},
+		{"GET", "/funds/BBB/prices", "", 200, "application/json", "[]\n"},
+		{"GET", "/funds/CCC", "", 404, "application/json", `{"error":"fund not found: CCC"}` + "\n"},
+		{"GET", "/funds/CCC/prices", "", 404, "application/json", `{"error":"fund not found: CCC"}` + "\n"},
+		{"GET", "/funds?format=xml", "", 400, "application/json", `{"error":"unsupported format: xml"}` + "\n"},
+		{"POST", "/fetch", "", 403, "application/json", `{"error":"write operations are not allowed"}` + "\n"},
+	} {
+		var header http.Header
+		if tc.accept != "" {
+			header = http.Header{"Accept": {tc.accept}}
+		}
+		status, h, body := doRequest(t, tc.method, ts.URL+tc.path, header)
+		if status != tc.status {
+			t.Errorf("%s %s: unexpected status: want=%d got=%d", tc.method, tc.path, tc.status, status)
+		}
+		if got := h.Get("Content-Type"); got != tc.ctype {
+			t.Errorf("%s %s: unexpected content type: want=%q got=%q", tc.method, tc.path, tc.ctype, got)
+		}
+		if body != tc.body {
+			t.Errorf("%s %s: unexpected body:\nwant=%s\ngot=%s", tc.method, tc.path, tc.body, body)
+		}
+	}
+	if status, _, _ := doRequest(t, "GET", ts.URL+"/funds/AAA/prices?from=2024-13-01", nil); status != 400 {
+		t.Errorf("invalid date should be 400: got=%d", status)
+	}
+	if status, _, _ := doRequest(t, "DELETE", ts.URL+"/funds/AAA", nil); status != 405 {
+		t.Errorf("unsupported method should be 405: got=%d", status)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	s, ts := newTestServer(t, true)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.Fetcher = &fetcher.Fetcher{
+		Fetch: func(ctx context.Context, fetchID string) (fundprice.Price, error) {
+			if fetchID == "test:b" {
+				return nil, errors.New("failure")
+			}
+			close(started)
+			<-release
+			return testPrice{date: time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC), value: 10200}, nil
+		},
+	}
+
+	type result struct {
+		status int
+		body   string
+	}
+	done := make(chan result)
+	go func() {
+		status, _, body := doRequest(t, "POST", ts.URL+"/fetch", nil)
+		done <- result{status, body}
+	}()
+	<-started
+	if status, _, _ := doRequest(t, "POST", ts.URL+"/fetch", nil); status != 409 {
+		t.Errorf("concurrent fetch should be 409: got=%d", status)
+	}
+	close(release)
+	r := <-done
+	if r.status != 200 {
+		t.Fatalf("unexpected status: %d %s", r.status, r.body)
+	}
+	var resp struct {
+		RunID  int64 `json:"run_id"`
+		Total  int   `json:"total"`
+		Failed int   `json:"failed"`
+		Errors []struct {
+			FundID string `json:"fund_id"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(r.body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 2 || resp.Failed != 1 || len(resp.Errors) != 1 || resp.Errors[0].FundID != "BBB" {
+		t.Errorf("unexpected response: %s", r.body)
+	}
+
+	_, _, body := doRequest(t, "GET", ts.URL+"/funds/AAA/prices?from=2024-01-09", nil)
+	if !strings.Contains(body, `"date":"2024-01-09","value":10200`) {
+		t.Errorf("fetched price is not stored: %s", body)
+	}
+}
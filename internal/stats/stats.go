@@ -0,0 +1,170 @@
+// Package stats calculates performance statistics of a price series.
+package stats
+
+import (
+	"math"
+	"time"
+)
+
+// TradingDays is the number of trading days in a year, used to annualize
+// daily statistics.
+const TradingDays = 245
+
+// Point is a value at a date.
+type Point struct {
+	Date  time.Time
+	Value float64
+}
+
+// Series is a list of points in ascending order of dates.
+type Series []Point
+
+// TotalReturn converts a price series to a total return index, which
+// reinvests distributions at their ex-dates. dists maps an ex-date (by
+// time.DateOnly format) to an amount per the same units as prices. The
+// index starts with the first price.
+func TotalReturn(s Series, dists map[string]float64) Series {
+	if len(s) == 0 {
+		return nil
+	}
+	tr := make(Series, len(s))
+	tr[0] = s[0]
+	for i := 1; i < len(s); i++ {
+		prev, curr := s[i-1].Value, s[i].Value
+		d := dists[s[i].Date.Format(time.DateOnly)]
+		r := 1.0
+		if prev != 0 {
+			r = (curr + d) / prev
+		}
+		tr[i] = Point{Date: s[i].Date, Value: tr[i-1].Value * r}
+	}
+	return tr
+}
+
+// valueAt returns the value of the last point on or before t.
+func (s Series) valueAt(t time.Time) (float64, bool) {
+	for i := len(s) - 1; i >= 0; i-- {
+		if !s[i].Date.After(t) {
+			return s[i].Value, true
+		}
+	}
+	return 0, false
+}
+
+// PeriodReturn returns a ratio of change during months before the last
+// point. It returns false when the series doesn't cover the period.
+func (s Series) PeriodReturn(months int) (float64, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	last := s[len(s)-1]
+	start := last.Date.AddDate(0, -months, 0)
+	if s[0].Date.After(start) {
+		return 0, false
+	}
+	base, ok := s.valueAt(start)
+	if !ok || base == 0 {
+		return 0, false
+	}
+	return last.Value/base - 1, true
+}
+
+// Return returns a ratio of change from the first point to the last.
+func (s Series) Return() (float64, bool) {
+	if len(s) < 2 || s[0].Value == 0 {
+		return 0, false
+	}
+	return s[len(s)-1].Value/s[0].Value - 1, true
+}
+
+// years returns length of the series in years.
+func (s Series) years() float64 {
+	if len(s) < 2 {
+		return 0
+	}
+	return s[len(s)-1].Date.Sub(s[0].Date).Hours() / 24 / 365.25
+}
+
+// AnnualizedReturn returns the compound annual growth rate of the series.
+func (s Series) AnnualizedReturn() (float64, bool) {
+	r, ok := s.Return()
+	y := s.years()
+	if !ok || y <= 0 || r <= -1 {
+		return 0, false
+	}
+	return math.Pow(1+r, 1/y) - 1, true
+}
+
+// AnnualizedVolatility returns the standard deviation of daily log returns,
+// annualized by TradingDays.
+func (s Series) AnnualizedVolatility() (float64, bool) {
+	if len(s) < 3 {
+		return 0, false
+	}
+	rets := make([]float64, 0, len(s)-1)
+	for i := 1; i < len(s); i++ {
+		if s[i-1].Value <= 0 || s[i].Value <= 0 {
+			continue
+		}
+		rets = append(rets, math.Log(s[i].Value/s[i-1].Value))
+	}
+	if len(rets) < 2 {
+		return 0, false
+	}
+	var sum float64
+	for _, r := range rets {
+		sum += r
+	}
+	mean := sum / float64(len(rets))
+	var sq float64
+	for _, r := range rets {
+		sq += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(sq/float64(len(rets)-1)) * math.Sqrt(TradingDays), true
+}
+
+// Drawdown is the largest decline from a peak to a following trough.
+type Drawdown struct {
+	Ratio  float64 // Ratio of decline, zero or negative
+	Peak   time.Time
+	Trough time.Time
+}
+
+// MaxDrawdown returns the maximum drawdown of the series.
+func (s Series) MaxDrawdown() (Drawdown, bool) {
+	if len(s) == 0 {
+		return Drawdown{}, false
+	}
+	var (
+		dd   Drawdown
+		peak = s[0]
+	)
+	dd.Peak, dd.Trough = peak.Date, peak.Date
+	for _, p := range s[1:] {
+		if p.Value > peak.Value {
+			peak = p
+			continue
+		}
+		if peak.Value == 0 {
+			continue
+		}
+		if r := p.Value/peak.Value - 1; r < dd.Ratio {
+			dd = Drawdown{Ratio: r, Peak: peak.Date, Trough: p.Date}
+		}
+	}
+	return dd, true
+}
+
+// SharpeRatio returns the Sharpe ratio of the series with an annual risk
+// free rate.
+func (s Series) SharpeRatio(riskFree float64) (float64, bool) {
+	r, ok := s.AnnualizedReturn()
+	if !ok {
+		return 0, false
+	}
+	v, ok := s.AnnualizedVolatility()
+	if !ok || v == 0 {
+		return 0, false
+	}
+	return (r - riskFree) / v, true
+}
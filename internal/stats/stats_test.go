@@ -0,0 +1,115 @@
+package stats_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/stats"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPeriodReturn(t *testing.T) {
+	s := stats.Series{
+		{day(2023, 1, 4), 10000},
+		{day(2023, 6, 30), 11000},
+		{day(2023, 11, 30), 12000},
+		{day(2023, 12, 29), 12200},
+		{day(2024, 1, 4), 12500},
+	}
+	for _, c := range []struct {
+		months int
+		want   float64
+		ok     bool
+	}{
+		{1, 12500.0/12000 - 1, true}, // 2023-12-04 falls back to 2023-11-30
+		{6, 12500.0/11000 - 1, true},
+		{12, 0.25, true},
+		{36, 0, false},
+	} {
+		got, ok := s.PeriodReturn(c.months)
+		if ok != c.ok || !near(got, c.want) {
+			t.Errorf("unmatch %dm: want=(%f,%t) got=(%f,%t)", c.months, c.want, c.ok, got, ok)
+		}
+	}
+	if r, ok := s.Return(); !ok || !near(r, 0.25) {
+		t.Errorf("unmatch return: %f %t", r, ok)
+	}
+}
+
+func TestAnnualizedReturn(t *testing.T) {
+	s := stats.Series{
+		{day(2020, 1, 1), 10000},
+		{day(2022, 1, 1), 12100},
+	}
+	got, ok := s.AnnualizedReturn()
+	if !ok || math.Abs(got-0.1) > 1e-3 {
+		t.Errorf("unmatch annualized return: %f %t", got, ok)
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	s := stats.Series{
+		{day(2024, 1, 1), 100},
+		{day(2024, 1, 2), 120},
+		{day(2024, 1, 3), 90},
+		{day(2024, 1, 4), 130},
+		{day(2024, 1, 5), 110},
+	}
+	dd, ok := s.MaxDrawdown()
+	if !ok || !near(dd.Ratio, -0.25) || !dd.Peak.Equal(day(2024, 1, 2)) || !dd.Trough.Equal(day(2024, 1, 3)) {
+		t.Errorf("unexpected drawdown: %+v", dd)
+	}
+}
+
+func TestVolatility(t *testing.T) {
+	// constant growth has no volatility.
+	var s stats.Series
+	v := 100.0
+	for i := range 10 {
+		s = append(s, stats.Point{day(2024, 1, 1+i), v})
+		v *= 1.01
+	}
+	if got, ok := s.AnnualizedVolatility(); !ok || !near(got, 0) {
+		t.Errorf("unexpected volatility: %f %t", got, ok)
+	}
+	if _, ok := s.SharpeRatio(0); ok {
+		t.Error("sharpe ratio should not be available for zero volatility")
+	}
+
+	s = stats.Series{
+		{day(2024, 1, 1), 100},
+		{day(2024, 1, 2), 110},
+		{day(2024, 1, 3), 100},
+		{day(2024, 1, 4), 110},
+	}
+	d := math.Log(1.1)
+	// sample stddev of {d, -d, d}.
+	mean := d / 3
+	sd := math.Sqrt((2*(d-mean)*(d-mean) + (-d-mean)*(-d-mean)) / 2)
+	if got, ok := s.AnnualizedVolatility(); !ok || !near(got, sd*math.Sqrt(stats.TradingDays)) {
+		t.Errorf("unexpected volatility: %f %t", got, ok)
+	}
+}
+
+func TestTotalReturn(t *testing.T) {
+	s := stats.Series{
+		{day(2024, 1, 1), 10000},
+		{day(2024, 1, 2), 9500}, // ex-date of 500 distribution
+		{day(2024, 1, 3), 9500},
+	}
+	tr := stats.TotalReturn(s, map[string]float64{"2024-01-02": 500})
+	if r, ok := tr.Return(); !ok || !near(r, 0) {
+		t.Errorf("total return should be zero: %f %t", r, ok)
+	}
+	if r, ok := s.Return(); !ok || !near(r, -0.05) {
+		t.Errorf("price return should be -5%%: %f %t", r, ok)
+	}
+}
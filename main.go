@@ -7,13 +7,19 @@ import (
 	"github.com/koron-go/subcmd"
 	"github.com/koron/funddb/subcmds/database"
 	"github.com/koron/funddb/subcmds/fund"
+	"github.com/koron/funddb/subcmds/portfolio"
 	"github.com/koron/funddb/subcmds/price"
+	"github.com/koron/funddb/subcmds/serve"
+	"github.com/koron/funddb/subcmds/txn"
 )
 
 var commandSet = subcmd.DefineRootSet(
 	price.Set,
 	fund.Set,
 	database.Set,
+	txn.Set,
+	portfolio.Set,
+	serve.Command,
 )
 
 func main() {
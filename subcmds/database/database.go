@@ -2,31 +2,125 @@ package database
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
 
 	"github.com/koron-go/subcmd"
 	"github.com/koron/funddb/internal/appcore"
 	"github.com/koron/funddb/internal/dataobj"
 )
 
-var InitSchema = subcmd.DefineCommand("initschema", "Initialize schema by statements", func(ctx context.Context, args []string) error {
-	ac, _, err := appcore.New(ctx, args)
+var InitSchema = subcmd.DefineCommand("initschema", "Initialize schema (deprecated: use \"migrate\")", func(ctx context.Context, args []string) error {
+	ac, _, err := appcore.NewForMigration(ctx, args)
 	if err != nil {
 		return err
 	}
 	defer ac.Close()
-	return dataobj.InitSchema(ac.ORM, ac.ShowSQL)
+	return dataobj.Migrate(ac.ORM, ac.ShowSQL)
 })
 
-var SyncORM = subcmd.DefineCommand("syncorm", "Sync schema with ORM", func(ctx context.Context, args []string) error {
-	ac, _, err := appcore.New(ctx, args)
+func migrateUp(ctx context.Context, args []string) error {
+	ac, _, err := appcore.NewForMigration(ctx, args)
 	if err != nil {
 		return err
 	}
 	defer ac.Close()
-	return ac.ORM.Sync(dataobj.Beans...)
+	return dataobj.Migrate(ac.ORM, ac.ShowSQL)
+}
+
+func migrateStatus(ctx context.Context, args []string) error {
+	ac, _, err := appcore.NewForMigration(ctx, args)
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	list, err := dataobj.MigrationStatuses(ac.ORM)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT\tNAME")
+	for _, m := range list {
+		status, at := "pending", "-"
+		if m.Applied {
+			status, at = "applied", m.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.Version, status, at, m.Name)
+	}
+	return nil
+}
+
+func migrateDown(ctx context.Context, args []string) error {
+	var (
+		to  = -1
+		yes bool
+	)
+	ac, _, err := appcore.NewForMigration(ctx, args, func(fs *flag.FlagSet) {
+		fs.IntVar(&to, "to", -1, "revert migrations newer than this version (default: revert the latest one)")
+		fs.BoolVar(&yes, "yes", false, "revert without confirmation")
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if to < 0 {
+		latest, err := dataobj.LatestVersion(ac.ORM)
+		if err != nil {
+			return err
+		}
+		if latest == 0 {
+			return errors.New("no migrations to revert")
+		}
+		to = latest - 1
+	}
+	if !yes {
+		list, err := dataobj.MigrationStatuses(ac.ORM)
+		if err != nil {
+			return err
+		}
+		var n int
+		for _, m := range slices.Backward(list) {
+			if m.Applied && m.Version > to {
+				fmt.Fprintf(os.Stderr, "revert #%d %s\n", m.Version, m.Name)
+				n++
+			}
+		}
+		if n == 0 {
+			return errors.New("no migrations to revert")
+		}
+		ok, err := appcore.Confirm(fmt.Sprintf("revert %d migrations, and drop their tables and columns with data?", n))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("canceled to revert migrations")
+		}
+	}
+	return dataobj.MigrateDown(ac.ORM, to, ac.ShowSQL)
+}
+
+var Migrate = subcmd.DefineCommand("migrate", "Migrate schema: migrate [up|status|down] [options]", func(ctx context.Context, args []string) error {
+	action := "up"
+	if len(args) > 0 && (args[0] == "up" || args[0] == "status" || args[0] == "down") {
+		action, args = args[0], args[1:]
+	}
+	switch action {
+	case "status":
+		return migrateStatus(ctx, args)
+	case "down":
+		return migrateDown(ctx, args)
+	default:
+		return migrateUp(ctx, args)
+	}
 })
 
 var Set = subcmd.DefineSet("database", "operate database",
+	Migrate,
 	InitSchema,
-	SyncORM,
 )
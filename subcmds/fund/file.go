@@ -0,0 +1,268 @@
+package fund
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/koron/funddb/internal/dataobj"
+)
+
+// fileFormat is a format of fund files, which is a flag.Value.
+type fileFormat string
+
+const (
+	formatTSV  fileFormat = "tsv"
+	formatCSV  fileFormat = "csv"
+	formatJSON fileFormat = "json"
+)
+
+var fileFormats = []fileFormat{formatTSV, formatCSV, formatJSON}
+
+func (f *fileFormat) String() string {
+	return string(*f)
+}
+
+func (f *fileFormat) Set(s string) error {
+	if !slices.Contains(fileFormats, fileFormat(s)) {
+		return fmt.Errorf("unknown format %q, available formats are: tsv|csv|json", s)
+	}
+	*f = fileFormat(s)
+	return nil
+}
+
+// formatOf guesses a format of a file by its extension. TSV is used for
+// unknown extensions.
+func formatOf(fname string) fileFormat {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".csv":
+		return formatCSV
+	case ".json":
+		return formatJSON
+	default:
+		return formatTSV
+	}
+}
+
+// fundColumns is the order of columns in fund files.
+var fundColumns = []string{"id", "name", "url", "fetch_id"}
+
+// fundRecord is a fund read from a file, with its location.
+type fundRecord struct {
+	dataobj.Fund
+	File string
+	Line int
+}
+
+// jsonFund is a fund in JSON files.
+type jsonFund struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	FetchID string `json:"fetch_id,omitempty"`
+}
+
+// lineError is an error at a line of a file.
+type lineError struct {
+	Line int
+	Err  error
+}
+
+func (e *lineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *lineError) Unwrap() error {
+	return e.Err
+}
+
+// readFile reads funds from a file. An empty format is guessed from the
+// extension of the file. Errors have the file name and the line number.
+func readFile(fname string, format fileFormat) ([]fundRecord, error) {
+	if format == "" {
+		format = formatOf(fname)
+	}
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var list []fundRecord
+	switch format {
+	case formatJSON:
+		list, err = readJSON(b)
+	default:
+		comma := '\t'
+		if format == formatCSV {
+			comma = ','
+		}
+		list, err = readCSV(bytes.NewReader(b), comma)
+	}
+	if err == nil {
+		for i := range list {
+			if err = validateRecord(&list[i]); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		var le *lineError
+		if errors.As(err, &le) {
+			return nil, fmt.Errorf("%s:%d: %w", fname, le.Line, le.Err)
+		}
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return nil, fmt.Errorf("%s:%d: %w", fname, pe.Line, pe.Err)
+		}
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	for i := range list {
+		list[i].File = fname
+	}
+	return list, nil
+}
+
+// validateRecord checks required fields of a record, and normalizes its
+// fetch ID.
+func validateRecord(r *fundRecord) error {
+	if r.ID == "" || r.Name == "" || r.URL == "" {
+		return &lineError{Line: r.Line, Err: errors.New("id, name and url are required")}
+	}
+	if r.FetchID != "" {
+		fetchID, err := normalizeFetchID(r.FetchID, r.ID)
+		if err != nil {
+			return &lineError{Line: r.Line, Err: err}
+		}
+		r.FetchID = fetchID
+	}
+	return nil
+}
+
+// readJSON reads funds from an array of JSON objects. Lines of records are
+// ones where the objects start.
+func readJSON(b []byte) ([]fundRecord, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('[') {
+		return nil, errors.New("require an array of funds")
+	}
+	var list []fundRecord
+	for dec.More() {
+		// skip a separator and spaces to find the start of an object.
+		off := int(dec.InputOffset())
+		off += len(b[off:]) - len(bytes.TrimLeft(b[off:], ", \t\r\n"))
+		line := bytes.Count(b[:off], []byte("\n")) + 1
+		var item jsonFund
+		if err := dec.Decode(&item); err != nil {
+			return nil, &lineError{Line: line, Err: err}
+		}
+		list = append(list, fundRecord{
+			Fund: dataobj.Fund{
+				ID:      strings.TrimSpace(item.ID),
+				Name:    strings.TrimSpace(item.Name),
+				URL:     strings.TrimSpace(item.URL),
+				FetchID: strings.TrimSpace(item.FetchID),
+			},
+			Line: line,
+		})
+	}
+	return list, nil
+}
+
+// readCSV reads funds from CSV or TSV. When the first row is a header,
+// which has "id", "name" and "url" columns at least, columns are mapped by
+// names. Otherwise columns are in order of id, name, url and fetch_id.
+func readCSV(rd io.Reader, comma rune) ([]fundRecord, error) {
+	r := csv.NewReader(rd)
+	r.Comma = comma
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	// allow quotes in names of hand-written TSV.
+	r.LazyQuotes = comma == '\t'
+	index := map[string]int{"id": 0, "name": 1, "url": 2, "fetch_id": 3}
+	var list []fundRecord
+	for first := true; ; first = false {
+		records, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		if first && isHeader(records) {
+			index = map[string]int{}
+			for i, s := range records {
+				index[strings.ToLower(strings.TrimSpace(s))] = i
+			}
+			continue
+		}
+		get := func(col string) string {
+			i, ok := index[col]
+			if !ok || i >= len(records) {
+				return ""
+			}
+			return strings.TrimSpace(records[i])
+		}
+		if len(records) < 3 {
+			return nil, &lineError{Line: line, Err: errors.New("few records, require 3 at least")}
+		}
+		list = append(list, fundRecord{
+			Fund: dataobj.Fund{
+				ID:      get("id"),
+				Name:    get("name"),
+				URL:     get("url"),
+				FetchID: get("fetch_id"),
+			},
+			Line: line,
+		})
+	}
+	return list, nil
+}
+
+// isHeader checks a row is a header.
+func isHeader(records []string) bool {
+	var n int
+	for _, s := range records {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "id", "name", "url":
+			n++
+		}
+	}
+	return n == 3
+}
+
+// writeFunds writes funds in a format, which readFile accepts.
+func writeFunds(w io.Writer, format fileFormat, funds []dataobj.Fund) error {
+	if format == formatJSON {
+		items := make([]jsonFund, len(funds))
+		for i, f := range funds {
+			items[i] = jsonFund{ID: f.ID, Name: f.Name, URL: f.URL, FetchID: f.FetchID}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(items)
+	}
+	cw := csv.NewWriter(w)
+	if format == formatTSV {
+		cw.Comma = '\t'
+	}
+	if err := cw.Write(fundColumns); err != nil {
+		return err
+	}
+	for _, f := range funds {
+		if err := cw.Write([]string{f.ID, f.Name, f.URL, f.FetchID}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -2,60 +2,98 @@ package fund
 
 import (
 	"context"
-	"encoding/csv"
 	"errors"
+	"flag"
 	"fmt"
-	"io"
+	"log"
 	"os"
 	"strings"
 
 	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/adapter"
 	"github.com/koron/funddb/internal/appcore"
 	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
 	"github.com/koron/funddb/internal/xormhelper"
 	"xorm.io/xorm"
 )
 
-func importFile(ctx context.Context, session *xorm.Session, fname string) error {
-	f, err := os.Open(fname)
+// upsertFund inserts or updates a fund. fetch_id of an existing fund is kept
+// when it is empty, "fund modify" with an empty -fetch-id clears it.
+func upsertFund(session *xorm.Session, fund dataobj.Fund) error {
+	has, err := session.Exist(&dataobj.Fund{ID: fund.ID})
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	r := csv.NewReader(f)
-	r.Comma = '\t'
-	r.Comment = '#'
-	r.ReuseRecord = true
-	for {
-		r.FieldsPerRecord = 0
-		records, err := r.Read()
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			return err
-		}
-		if len(records) < 3 {
-			return errors.New("few records, require 3 at least")
-		}
-		fund := dataobj.Fund{
-			ID:   strings.TrimSpace(records[0]),
-			Name: strings.TrimSpace(records[1]),
-			URL:  strings.TrimSpace(records[2]),
+	if !has {
+		if fund.FetchID == "" {
+			// keep fetch_id NULL to avoid conflicts on UQE_funds_fetch_id.
+			session.Omit("fetch_id")
 		}
-		if len(records) >= 4 {
-			fund.FetchID = strings.TrimSpace(records[3])
-		}
-		err = xormhelper.UpsertOne(session, fund.ID, &fund)
+		_, err := session.Insert(&fund)
+		return err
+	}
+	cols := map[string]any{"name": fund.Name, "url": fund.URL}
+	if fund.FetchID != "" {
+		cols["fetch_id"] = fund.FetchID
+	}
+	_, err = session.Table(&dataobj.Fund{}).ID(fund.ID).Update(cols)
+	return err
+}
+
+// fundDependents are tables which have rows for funds, with their columns
+// of fund IDs.
+var fundDependents = []struct {
+	name   string
+	bean   any
+	column string
+}{
+	{"prices", &dataobj.Price{}, "id"},
+	{"distributions", &dataobj.Distribution{}, "id"},
+	{"transactions", &dataobj.Transaction{}, "fund_id"},
+	{"fetch results", &dataobj.FetchResult{}, "fund_id"},
+	{"holidays", &dataobj.FundHoliday{}, "fund_id"},
+	{"metrics", &dataobj.FundMetric{}, "fund_id"},
+}
+
+// countDependents describes numbers of dependent rows of a fund, like
+// "10 prices, 1 distributions, ...".
+func countDependents(session *xorm.Session, id string) (string, error) {
+	counts := make([]string, 0, len(fundDependents))
+	for _, dep := range fundDependents {
+		n, err := session.Where(dep.column+" = ?", id).Count(dep.bean)
 		if err != nil {
+			return "", err
+		}
+		counts = append(counts, fmt.Sprintf("%d %s", n, dep.name))
+	}
+	return strings.Join(counts, ", "), nil
+}
+
+// deleteFund deletes a fund with its dependent rows.
+func deleteFund(session *xorm.Session, id string) error {
+	// delete dependent rows explicitly, as SQLite doesn't enforce foreign
+	// keys by default.
+	for _, dep := range fundDependents {
+		if _, err := session.Where(dep.column+" = ?", id).Delete(dep.bean); err != nil {
 			return err
 		}
 	}
-	return nil
+	_, err := session.Delete(&dataobj.Fund{ID: id})
+	return err
 }
 
-var Import = subcmd.DefineCommand("import", "import funds from TSV file (id, name, url, fetch_id)", func(ctx context.Context, args []string) error {
-	ac, files, err := appcore.New(ctx, args)
+var Import = subcmd.DefineCommand("import", "import funds from TSV, CSV or JSON files (id, name, url, fetch_id)", func(ctx context.Context, args []string) error {
+	var (
+		format fileFormat
+		prune  bool
+		dryRun bool
+	)
+	ac, files, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&format, "format", "format of files: tsv|csv|json (default: guessed by extensions)")
+		fs.BoolVar(&prune, "prune", false, "delete funds which are missing from the files")
+		fs.BoolVar(&dryRun, "dry-run", false, "print a plan for each fund without writing")
+	})
 	if err != nil {
 		return err
 	}
@@ -63,38 +101,214 @@ var Import = subcmd.DefineCommand("import", "import funds from TSV file (id, nam
 	if len(files) == 0 {
 		return errors.New("no files to import as fund")
 	}
+	var records []fundRecord
+	for _, f := range files {
+		list, err := readFile(f, format)
+		if err != nil {
+			return err
+		}
+		records = append(records, list...)
+	}
 	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
-		for _, f := range files {
-			err := importFile(ctx, session, f)
-			if err != nil {
-				return err
+		plan, err := makePlan(session, records, prune)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			printPlan(os.Stdout, plan)
+			printSummary(os.Stdout, plan)
+			if n := countActions(plan)[actConflict]; n > 0 {
+				return fmt.Errorf("%d conflicts found", n)
+			}
+			return nil
+		}
+		var conflicts []error
+		for _, item := range plan {
+			if item.Action == actConflict {
+				conflicts = append(conflicts, fmt.Errorf("%s: conflict %s: %s", item.pos(), item.Record.ID, item.Reason))
 			}
 		}
+		if len(conflicts) > 0 {
+			return errors.Join(conflicts...)
+		}
+		for _, item := range plan {
+			switch item.Action {
+			case actInsert, actUpdate:
+				if err := upsertFund(session, item.Record.Fund); err != nil {
+					return fmt.Errorf("%s: %w", item.pos(), err)
+				}
+			case actDelete:
+				if err := deleteFund(session, item.Old.ID); err != nil {
+					return err
+				}
+				log.Printf("pruned fund %s (%s)", item.Old.ID, item.Old.Name)
+			}
+		}
+		printSummary(os.Stdout, plan)
 		return nil
 	})
 })
 
-var List = subcmd.DefineCommand("list", "list funds", func(ctx context.Context, args []string) error {
-	ac, _, err := appcore.New(ctx, args)
+var Export = subcmd.DefineCommand("export", "export funds in a format which import accepts", func(ctx context.Context, args []string) error {
+	format := formatTSV
+	ac, _, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&format, "format", "output format: tsv|csv|json")
+	})
 	if err != nil {
 		return err
 	}
 	defer ac.Close()
+	var funds []dataobj.Fund
+	if err := ac.ORM.OrderBy("id").Find(&funds); err != nil {
+		return err
+	}
+	return writeFunds(os.Stdout, format, funds)
+})
 
-	return ac.ORM.Iterate(&dataobj.Fund{}, func(idx int, bean interface{}) error {
-		f := bean.(*dataobj.Fund)
-		fmt.Printf("%+v\n", *f)
-		return nil
+var List = subcmd.DefineCommand("list", "list funds", func(ctx context.Context, args []string) error {
+	var format string
+	ac, _, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
 	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	var funds []dataobj.Fund
+	if err := ac.ORM.OrderBy("id").Find(&funds); err != nil {
+		return err
+	}
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader(fundColumns); err != nil {
+		return err
+	}
+	for _, fund := range funds {
+		var fetchID any
+		if fund.FetchID != "" {
+			fetchID = fund.FetchID
+		}
+		if err := w.WriteRow([]any{fund.ID, fund.Name, fund.URL, fetchID}); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
 })
 
-var Add = subcmd.DefineCommand("add", "add a fund", func(ctx context.Context, args []string) error {
-	// TODO:
+// stringFlag is a flag.Value for a string which remembers it is set or not.
+type stringFlag struct {
+	value string
+	set   bool
+}
+
+func (sf *stringFlag) String() string {
+	return sf.value
+}
+
+func (sf *stringFlag) Set(s string) error {
+	sf.value = strings.TrimSpace(s)
+	sf.set = true
+	return nil
+}
+
+// checkUnique checks values of unique columns are not used by other funds.
+func checkUnique(session *xorm.Session, id string, cols map[string]any) error {
+	for _, col := range []string{"name", "url", "fetch_id"} {
+		v, ok := cols[col]
+		if !ok || v == nil {
+			continue
+		}
+		var other dataobj.Fund
+		has, err := session.Where(col+" = ? AND id != ?", v, id).Get(&other)
+		if err != nil {
+			return err
+		}
+		if has {
+			return fmt.Errorf("%s %q is used by fund %s already", col, v, other.ID)
+		}
+	}
 	return nil
+}
+
+// normalizeFetchID fills a default ID of a fetch ID for a fund, like
+// "ammufg:" to "ammufg:assoc={fundID}", and checks it has a known scheme.
+func normalizeFetchID(fetchID, fundID string) (string, error) {
+	fetchID = adapter.ExpandFetchID(fetchID, fundID)
+	if err := adapter.Validate(fetchID); err != nil {
+		return "", fmt.Errorf("invalid fetch ID: %w (see \"price schemes\" for known schemes)", err)
+	}
+	return fetchID, nil
+}
+
+// moveFirstArg moves the first argument to the end, when it is not a flag.
+// It enables to put flags after an ID: "modify ID -name NAME".
+func moveFirstArg(args []string) []string {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") {
+		return args
+	}
+	return append(append([]string{}, args[1:]...), args[0])
+}
+
+var Add = subcmd.DefineCommand("add", "add a fund", func(ctx context.Context, args []string) error {
+	var fund dataobj.Fund
+	ac, _, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&fund.ID, "id", "", "association ID of the fund (required)")
+		fs.StringVar(&fund.Name, "name", "", "name of the fund (required)")
+		fs.StringVar(&fund.URL, "url", "", "URL of the fund (required)")
+		fs.StringVar(&fund.FetchID, "fetch-id", "", "fetch ID {scheme}:{id}")
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+
+	fund.ID = strings.TrimSpace(fund.ID)
+	fund.Name = strings.TrimSpace(fund.Name)
+	fund.URL = strings.TrimSpace(fund.URL)
+	fund.FetchID = strings.TrimSpace(fund.FetchID)
+	if fund.ID == "" || fund.Name == "" || fund.URL == "" {
+		return errors.New("-id, -name and -url are required")
+	}
+	cols := map[string]any{"name": fund.Name, "url": fund.URL}
+	if fund.FetchID != "" {
+		fund.FetchID, err = normalizeFetchID(fund.FetchID, fund.ID)
+		if err != nil {
+			return err
+		}
+		cols["fetch_id"] = fund.FetchID
+	}
+
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		has, err := session.Exist(&dataobj.Fund{ID: fund.ID})
+		if err != nil {
+			return err
+		}
+		if has {
+			return fmt.Errorf("fund %s exists already", fund.ID)
+		}
+		if err := checkUnique(session, fund.ID, cols); err != nil {
+			return err
+		}
+		if fund.FetchID == "" {
+			// keep fetch_id NULL to avoid conflicts on UQE_funds_fetch_id.
+			session.Omit("fetch_id")
+		}
+		_, err = session.Insert(&fund)
+		return err
+	})
 })
 
-var Delete = subcmd.DefineCommand("delete", "delete a fund", func(ctx context.Context, args []string) error {
-	ac, params, err := appcore.New(ctx, args)
+var Delete = subcmd.DefineCommand("delete", "delete funds with their prices, transactions and other rows", func(ctx context.Context, args []string) error {
+	var yes bool
+	ac, params, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&yes, "yes", false, "delete without confirmation")
+	})
 	if err != nil {
 		return err
 	}
@@ -104,34 +318,104 @@ var Delete = subcmd.DefineCommand("delete", "delete a fund", func(ctx context.Co
 		return errors.New("required one or more ID of fund to be deleted")
 	}
 
-	session := ac.ORM.NewSession()
-	defer session.Close()
-	if err := session.Begin(); err != nil {
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		for _, id := range params {
+			var fund dataobj.Fund
+			has, err := session.ID(id).Get(&fund)
+			if err != nil {
+				return err
+			}
+			if !has {
+				return fmt.Errorf("no funds for id:%s", id)
+			}
+			if !yes {
+				counts, err := countDependents(session, id)
+				if err != nil {
+					return err
+				}
+				ok, err := appcore.Confirm(fmt.Sprintf("delete fund %s (%s) and its %s?", fund.ID, fund.Name, counts))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return fmt.Errorf("canceled to delete %s", id)
+				}
+			}
+			if err := deleteFund(session, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+})
+
+var Modify = subcmd.DefineCommand("modify", "modify a fund", func(ctx context.Context, args []string) error {
+	var name, url, fetchID stringFlag
+	ac, params, err := appcore.New(ctx, moveFirstArg(args), func(fs *flag.FlagSet) {
+		fs.Var(&name, "name", "new name of the fund")
+		fs.Var(&url, "url", "new URL of the fund")
+		fs.Var(&fetchID, "fetch-id", "new fetch ID {scheme}:{id}, empty to clear")
+	})
+	if err != nil {
 		return err
 	}
-	for _, id := range params {
-		n, err := session.Delete(&dataobj.Fund{ID: id})
+	defer ac.Close()
+
+	if len(params) != 1 {
+		return errors.New("required an ID of fund to be modified")
+	}
+	id := params[0]
+	cols := map[string]any{}
+	if name.set {
+		if name.value == "" {
+			return errors.New("empty -name is not allowed")
+		}
+		cols["name"] = name.value
+	}
+	if url.set {
+		if url.value == "" {
+			return errors.New("empty -url is not allowed")
+		}
+		cols["url"] = url.value
+	}
+	if fetchID.set {
+		if fetchID.value == "" {
+			cols["fetch_id"] = nil
+		} else {
+			v, err := normalizeFetchID(fetchID.value, id)
+			if err != nil {
+				return err
+			}
+			cols["fetch_id"] = v
+		}
+	}
+	if len(cols) == 0 {
+		return errors.New("nothing to modify, use -name, -url or -fetch-id")
+	}
+
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		has, err := session.Exist(&dataobj.Fund{ID: id})
 		if err != nil {
-			session.Rollback()
 			return err
 		}
-		if n == 0 {
-			session.Rollback()
+		if !has {
 			return fmt.Errorf("no funds for id:%s", id)
 		}
-	}
-	return session.Commit()
-})
-
-var Modify = subcmd.DefineCommand("modify", "modify a fund", func(ctx context.Context, args []string) error {
-	// TODO:
-	return nil
+		if err := checkUnique(session, id, cols); err != nil {
+			return err
+		}
+		_, err = session.Table(&dataobj.Fund{}).ID(id).Update(cols)
+		return err
+	})
 })
 
 var Set = subcmd.DefineSet("fund", "operate funds",
 	Import,
+	Export,
 	List,
-	//Add,
-	//Delete,
-	//Modify,
+	Add,
+	Delete,
+	Modify,
+	Holiday,
+	Metrics,
 )
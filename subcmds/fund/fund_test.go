@@ -0,0 +1,298 @@
+package fund
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"xorm.io/xorm"
+)
+
+// testDB is a migrated database in a temporary directory, with an empty
+// config file to isolate commands from settings of users.
+type testDB struct {
+	dir    string
+	dbfile string
+	config string
+}
+
+func newTestDB(t *testing.T) *testDB {
+	t.Helper()
+	dir := t.TempDir()
+	db := &testDB{
+		dir:    dir,
+		dbfile: filepath.Join(dir, "fund.db"),
+		config: filepath.Join(dir, "config.json"),
+	}
+	if err := os.WriteFile(db.config, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine := db.open(t)
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func (db *testDB) open(t *testing.T) *xorm.Engine {
+	t.Helper()
+	engine, err := dataobj.NewEngine(db.dbfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+// run runs a command with the database.
+func (db *testDB) run(cmd subcmd.Command, args ...string) error {
+	return cmd.Run(context.Background(), append([]string{"-config", db.config, "-dbfile", db.dbfile}, args...))
+}
+
+func (db *testDB) getFund(t *testing.T, id string) (dataobj.Fund, bool) {
+	t.Helper()
+	var fund dataobj.Fund
+	has, err := db.open(t).ID(id).Get(&fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fund, has
+}
+
+// dependents are tables which have rows for funds, with their columns of
+// fund IDs.
+var dependents = []struct {
+	bean   any
+	column string
+}{
+	{&dataobj.Price{}, "id"},
+	{&dataobj.FetchResult{}, "fund_id"},
+	{&dataobj.Distribution{}, "id"},
+	{&dataobj.Transaction{}, "fund_id"},
+	{&dataobj.FundHoliday{}, "fund_id"},
+	{&dataobj.FundMetric{}, "fund_id"},
+}
+
+// insertFundWithRows inserts a fund and a row for it to each dependent
+// table.
+func insertFundWithRows(t *testing.T, engine *xorm.Engine, id string) {
+	t.Helper()
+	// keep fetch_id NULL to avoid conflicts on UQE_funds_fetch_id.
+	if _, err := engine.Omit("fetch_id").Insert(&dataobj.Fund{ID: id, Name: "Fund " + id, URL: "https://example.com/" + id}); err != nil {
+		t.Fatal(err)
+	}
+	date := dataobj.NewDate(2024, 6, 24)
+	for _, bean := range []any{
+		&dataobj.Price{ID: id, Date: date, Value: 10000},
+		&dataobj.FetchResult{RunID: 1, FundID: id, FetchID: "test:" + id, Status: dataobj.FetchStatusOK},
+		&dataobj.Distribution{ID: id, ExDate: date, Amount: 100},
+		&dataobj.Transaction{AccountID: "acct", FundID: id, Date: date, Type: dataobj.TxnBuy, Units: 10000, Amount: 10000},
+		&dataobj.FundHoliday{FundID: id, Date: date},
+		&dataobj.FundMetric{FundID: id, Date: date, Name: "risk_1y", Value: "11.5"},
+	} {
+		if _, err := engine.Insert(bean); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkRows checks the number of rows for a fund in each dependent table.
+func checkRows(t *testing.T, engine *xorm.Engine, id string, want int64) {
+	t.Helper()
+	for _, dep := range dependents {
+		n, err := engine.Where(dep.column+" = ?", id).Count(dep.bean)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("unexpected number of rows for %s in %T: want=%d got=%d", id, dep.bean, want, n)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.run(Add, "-id", "A", "-name", "Fund A", "-url", "https://example.com/a"); err != nil {
+		t.Fatal(err)
+	}
+	fund, has := db.getFund(t, "A")
+	if !has || fund.Name != "Fund A" || fund.FetchID != "" {
+		t.Errorf("unexpected fund: %+v", fund)
+	}
+	// funds without fetch IDs don't conflict on UQE_funds_fetch_id.
+	if err := db.run(Add, "-id", "B", "-name", "Fund B", "-url", "https://example.com/b"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"duplicated ID", []string{"-id", "A", "-name", "Fund A2", "-url", "https://example.com/a2"}, "fund A exists already"},
+		{"duplicated name", []string{"-id", "C", "-name", "Fund A", "-url", "https://example.com/c"}, `name "Fund A" is used by fund A`},
+		{"duplicated URL", []string{"-id", "C", "-name", "Fund C", "-url", "https://example.com/a"}, `url "https://example.com/a" is used by fund A`},
+		{"unknown scheme", []string{"-id", "C", "-name", "Fund C", "-url", "https://example.com/c", "-fetch-id", "unknown:c"}, "invalid fetch ID"},
+		{"missing name", []string{"-id", "C", "-url", "https://example.com/c"}, "-id, -name and -url are required"},
+	} {
+		err := db.run(Add, tc.args...)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("unexpected error for %s: want=%q got=%v", tc.name, tc.wantErr, err)
+		}
+	}
+	if _, has := db.getFund(t, "C"); has {
+		t.Error("fund C should not be added")
+	}
+}
+
+func TestModify(t *testing.T) {
+	db := newTestDB(t)
+	for _, args := range [][]string{
+		{"-id", "A", "-name", "Fund A", "-url", "https://example.com/a"},
+		{"-id", "B", "-name", "Fund B", "-url", "https://example.com/b"},
+	} {
+		if err := db.run(Add, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.run(Modify, "-name", "Fund A2", "A"); err != nil {
+		t.Fatal(err)
+	}
+	if fund, _ := db.getFund(t, "A"); fund.Name != "Fund A2" || fund.URL != "https://example.com/a" {
+		t.Errorf("unexpected fund: %+v", fund)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"unknown ID", []string{"-name", "Fund Z", "Z"}, "no funds for id:Z"},
+		{"duplicated name", []string{"-name", "Fund B", "A"}, `name "Fund B" is used by fund B`},
+		{"empty name", []string{"-name", "", "A"}, "empty -name is not allowed"},
+		{"nothing", []string{"A"}, "nothing to modify"},
+		{"no IDs", []string{"-name", "Fund A3"}, "required an ID"},
+	} {
+		err := db.run(Modify, tc.args...)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("unexpected error for %s: want=%q got=%v", tc.name, tc.wantErr, err)
+		}
+	}
+	if _, has := db.getFund(t, "Z"); has {
+		t.Error("fund Z should not be created")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db := newTestDB(t)
+	engine := db.open(t)
+	insertFundWithRows(t, engine, "A")
+	insertFundWithRows(t, engine, "B")
+
+	if err := db.run(Delete, "-yes", "A"); err != nil {
+		t.Fatal(err)
+	}
+	if _, has := db.getFund(t, "A"); has {
+		t.Error("fund A should be deleted")
+	}
+	checkRows(t, engine, "A", 0)
+	checkRows(t, engine, "B", 1)
+
+	err := db.run(Delete, "-yes", "B", "Z")
+	if err == nil || !strings.Contains(err.Error(), "no funds for id:Z") {
+		t.Errorf("unexpected error for unknown ID: %v", err)
+	}
+	// deleting is rolled back.
+	if _, has := db.getFund(t, "B"); !has {
+		t.Error("fund B should not be deleted")
+	}
+	checkRows(t, engine, "B", 1)
+}
+
+func TestImportPrune(t *testing.T) {
+	db := newTestDB(t)
+	engine := db.open(t)
+	insertFundWithRows(t, engine, "A")
+	insertFundWithRows(t, engine, "B")
+
+	fname := filepath.Join(db.dir, "funds.tsv")
+	if err := os.WriteFile(fname, []byte("B\tFund B\thttps://example.com/B\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.run(Import, "-prune", fname); err != nil {
+		t.Fatal(err)
+	}
+	if _, has := db.getFund(t, "A"); has {
+		t.Error("fund A should be pruned")
+	}
+	checkRows(t, engine, "A", 0)
+	checkRows(t, engine, "B", 1)
+}
+
+func TestDeleteConfirm(t *testing.T) {
+	db := newTestDB(t)
+	engine := db.open(t)
+	insertFundWithRows(t, engine, "A")
+
+	session := engine.NewSession()
+	defer session.Close()
+	counts, err := countDependents(session, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1 prices, 1 distributions, 1 transactions, 1 fetch results, 1 holidays, 1 metrics"; counts != want {
+		t.Errorf("unexpected counts: want=%q got=%q", want, counts)
+	}
+
+	saved := appcore.Stdin
+	t.Cleanup(func() { appcore.Stdin = saved })
+	appcore.Stdin = bufio.NewReader(strings.NewReader("n\n"))
+	if err := db.run(Delete, "A"); err == nil || !strings.Contains(err.Error(), "canceled to delete A") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	checkRows(t, engine, "A", 1)
+	appcore.Stdin = bufio.NewReader(strings.NewReader("y\n"))
+	if err := db.run(Delete, "A"); err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, engine, "A", 0)
+}
+
+func TestImportKeepFetchID(t *testing.T) {
+	db := newTestDB(t)
+	engine := db.open(t)
+	if _, err := engine.Insert(&dataobj.Fund{ID: "A", Name: "Fund A", URL: "https://example.com/a", FetchID: "test:a"}); err != nil {
+		t.Fatal(err)
+	}
+	fname := filepath.Join(db.dir, "funds.csv")
+	if err := os.WriteFile(fname, []byte("id,name,url\nA,Fund A2,https://example.com/a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.run(Import, fname); err != nil {
+		t.Fatal(err)
+	}
+	fund, _ := db.getFund(t, "A")
+	if want := (dataobj.Fund{ID: "A", Name: "Fund A2", URL: "https://example.com/a", FetchID: "test:a"}); fund != want {
+		t.Errorf("unexpected fund: want=%+v got=%+v", want, fund)
+	}
+
+	session := engine.NewSession()
+	defer session.Close()
+	records, err := readFile(fname, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := makePlan(session, records, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || plan[0].Action != actUnchanged {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+}
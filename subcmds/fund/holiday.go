@@ -0,0 +1,113 @@
+package fund
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/xormhelper"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+var HolidayAdd = subcmd.DefineCommand("add", "add fund specific non-NAV days: [-note NOTE] ID DATE...", func(ctx context.Context, args []string) error {
+	var note string
+	ac, params, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&note, "note", "", "note of the days, like a name of a foreign holiday")
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if len(params) < 2 {
+		return errors.New("required an ID of fund and one or more dates")
+	}
+	id := params[0]
+	var days []dataobj.Date
+	for _, s := range params[1:] {
+		var d dataobj.Date
+		if err := d.Set(s); err != nil {
+			return fmt.Errorf("invalid date %q: %w", s, err)
+		}
+		days = append(days, d)
+	}
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		has, err := session.Exist(&dataobj.Fund{ID: id})
+		if err != nil {
+			return err
+		}
+		if !has {
+			return fmt.Errorf("no funds for id:%s", id)
+		}
+		for _, d := range days {
+			h := dataobj.FundHoliday{FundID: id, Date: d, Note: strings.TrimSpace(note)}
+			if err := xormhelper.UpsertOne(session, schemas.PK{h.FundID, h.Date}, h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+})
+
+var HolidayDelete = subcmd.DefineCommand("delete", "delete fund specific non-NAV days: ID DATE...", func(ctx context.Context, args []string) error {
+	ac, params, err := appcore.New(ctx, args)
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if len(params) < 2 {
+		return errors.New("required an ID of fund and one or more dates")
+	}
+	id := params[0]
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		for _, s := range params[1:] {
+			var d dataobj.Date
+			if err := d.Set(s); err != nil {
+				return fmt.Errorf("invalid date %q: %w", s, err)
+			}
+			n, err := session.Where("fund_id = ? AND date = ?", id, d).Delete(&dataobj.FundHoliday{})
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return fmt.Errorf("no holidays for id:%s date:%s", id, d)
+			}
+		}
+		return nil
+	})
+})
+
+var HolidayList = subcmd.DefineCommand("list", "list fund specific non-NAV days", func(ctx context.Context, args []string) error {
+	ac, ids, err := appcore.New(ctx, args)
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	session := ac.ORM.OrderBy("fund_id, date")
+	if len(ids) > 0 {
+		session.In("fund_id", ids)
+	}
+	var list []dataobj.FundHoliday
+	if err := session.Find(&list); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "FUND\tDATE\tNOTE")
+	for _, h := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", h.FundID, h.Date, h.Note)
+	}
+	return w.Flush()
+})
+
+var Holiday = subcmd.DefineSet("holiday", "operate fund specific non-NAV days",
+	HolidayAdd,
+	HolidayDelete,
+	HolidayList,
+)
@@ -0,0 +1,87 @@
+package fund
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
+)
+
+var Metrics = subcmd.DefineCommand("metrics", "show metrics which providers report for a fund: [-date DATE|-all] [-names NAMES] ID", func(ctx context.Context, args []string) error {
+	var (
+		date   dataobj.Date
+		all    bool
+		names  string
+		format string
+	)
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&date, "date", "date of metrics (YYYY-MM-DD, default: the latest)")
+		fs.BoolVar(&all, "all", false, "show metrics of all dates")
+		fs.StringVar(&names, "names", "", "comma separated names of metrics to show (default: all metrics)")
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if len(ids) != 1 {
+		return errors.New("require an ID of fund")
+	}
+	id := ids[0]
+	if all && !date.IsZero() {
+		return errors.New("-date and -all are exclusive")
+	}
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	session := ac.ORM.Where("fund_id = ?", id)
+	switch {
+	case all:
+	case !date.IsZero():
+		session.And("date = ?", date)
+	default:
+		var latest dataobj.FundMetric
+		has, err := ac.ORM.Where("fund_id = ?", id).Desc("date").Get(&latest)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return fmt.Errorf("no metrics for id:%s", id)
+		}
+		session.And("date = ?", latest.Date)
+	}
+	if names != "" {
+		session.In("name", strings.Split(names, ","))
+	}
+	var list []dataobj.FundMetric
+	if err := session.OrderBy("date, name").Find(&list); err != nil {
+		return err
+	}
+
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader([]string{"date", "name", "value", "ref_date"}); err != nil {
+		return err
+	}
+	for _, m := range list {
+		var refDate any
+		if !m.RefDate.IsZero() {
+			refDate = m.RefDate
+		}
+		if err := w.WriteRow([]any{m.Date, m.Name, m.Value, refDate}); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+})
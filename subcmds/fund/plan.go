@@ -0,0 +1,186 @@
+package fund
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/koron/funddb/internal/dataobj"
+	"xorm.io/xorm"
+)
+
+// action is an operation for a fund in an import plan.
+type action string
+
+const (
+	actInsert    action = "insert"
+	actUpdate    action = "update"
+	actUnchanged action = "unchanged"
+	actConflict  action = "conflict"
+	actDelete    action = "delete"
+)
+
+// fieldDiff is a change of a field of a fund.
+type fieldDiff struct {
+	Field    string
+	Old, New string
+}
+
+// planItem is a planned operation for a fund.
+type planItem struct {
+	Action action
+	Record fundRecord // zero for actDelete
+	Old    dataobj.Fund
+	Diffs  []fieldDiff
+	Reason string // for actConflict
+}
+
+// pos returns a location of the record in files.
+func (item planItem) pos() string {
+	if item.Record.File == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", item.Record.File, item.Record.Line)
+}
+
+// diffFunds compares fields of funds which are written in files.
+func diffFunds(a, b dataobj.Fund) []fieldDiff {
+	var diffs []fieldDiff
+	for _, f := range []struct {
+		name   string
+		av, bv string
+	}{
+		{"name", a.Name, b.Name},
+		{"url", a.URL, b.URL},
+		{"fetch_id", a.FetchID, b.FetchID},
+	} {
+		if f.av != f.bv {
+			diffs = append(diffs, fieldDiff{Field: f.name, Old: f.av, New: f.bv})
+		}
+	}
+	return diffs
+}
+
+// makePlan compares records with funds in the DB, and plans operations for
+// them. Records conflict when they have duplicated IDs, or when values of
+// unique columns are used by other funds after the import.
+func makePlan(session *xorm.Session, records []fundRecord, prune bool) ([]planItem, error) {
+	var funds []dataobj.Fund
+	if err := session.OrderBy("id").Find(&funds); err != nil {
+		return nil, err
+	}
+	current := make(map[string]dataobj.Fund, len(funds))
+	for _, f := range funds {
+		current[f.ID] = f
+	}
+
+	var plan []planItem
+	first := map[string]int{} // index of the first item for each ID
+	final := map[string]dataobj.Fund{}
+	if !prune {
+		for _, f := range funds {
+			final[f.ID] = f
+		}
+	}
+	for _, r := range records {
+		item := planItem{Record: r}
+		if i, ok := first[r.ID]; ok {
+			item.Action = actConflict
+			item.Reason = "duplicated id, which is at " + plan[i].pos()
+			plan = append(plan, item)
+			continue
+		}
+		first[r.ID] = len(plan)
+		old, ok := current[r.ID]
+		if ok && r.FetchID == "" {
+			// an empty fetch_id keeps the current one.
+			r.FetchID = old.FetchID
+			item.Record = r
+		}
+		final[r.ID] = r.Fund
+		switch {
+		case !ok:
+			item.Action = actInsert
+		default:
+			item.Old = old
+			item.Diffs = diffFunds(old, r.Fund)
+			item.Action = actUpdate
+			if len(item.Diffs) == 0 {
+				item.Action = actUnchanged
+			}
+		}
+		plan = append(plan, item)
+	}
+
+	// check unique columns in the final state.
+	for _, col := range []struct {
+		name  string
+		value func(dataobj.Fund) string
+	}{
+		{"name", func(f dataobj.Fund) string { return f.Name }},
+		{"url", func(f dataobj.Fund) string { return f.URL }},
+		{"fetch_id", func(f dataobj.Fund) string { return f.FetchID }},
+	} {
+		users := map[string][]string{}
+		for id, f := range final {
+			if v := col.value(f); v != "" {
+				users[v] = append(users[v], id)
+			}
+		}
+		for i, item := range plan {
+			if item.Action == actConflict {
+				continue
+			}
+			ids := users[col.value(item.Record.Fund)]
+			if len(ids) < 2 {
+				continue
+			}
+			slices.Sort(ids)
+			others := slices.DeleteFunc(slices.Clone(ids), func(id string) bool { return id == item.Record.ID })
+			plan[i].Action = actConflict
+			plan[i].Reason = fmt.Sprintf("%s %q is used by fund %s", col.name, col.value(item.Record.Fund), others[0])
+		}
+	}
+
+	if prune {
+		for _, f := range funds {
+			if _, ok := first[f.ID]; !ok {
+				plan = append(plan, planItem{Action: actDelete, Old: f})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// countActions counts items for each action.
+func countActions(plan []planItem) map[action]int {
+	counts := map[action]int{}
+	for _, item := range plan {
+		counts[item.Action]++
+	}
+	return counts
+}
+
+// printPlan prints items of a plan with field-level differences.
+func printPlan(w io.Writer, plan []planItem) {
+	for _, item := range plan {
+		switch item.Action {
+		case actDelete:
+			fmt.Fprintf(w, "%-9s %s (%s)\n", item.Action, item.Old.ID, item.Old.Name)
+		case actConflict:
+			fmt.Fprintf(w, "%-9s %s (%s): %s\n", item.Action, item.Record.ID, item.pos(), item.Reason)
+		default:
+			fmt.Fprintf(w, "%-9s %s (%s)\n", item.Action, item.Record.ID, item.pos())
+		}
+		for _, d := range item.Diffs {
+			fmt.Fprintf(w, "    %s: %q -> %q\n", d.Field, d.Old, d.New)
+		}
+	}
+}
+
+// printSummary prints numbers of operations.
+func printSummary(w io.Writer, plan []planItem) {
+	counts := countActions(plan)
+	fmt.Fprintf(w, "insert: %d, update: %d, unchanged: %d, delete: %d, conflict: %d\n",
+		counts[actInsert], counts[actUpdate], counts[actUnchanged], counts[actDelete], counts[actConflict])
+}
@@ -0,0 +1,130 @@
+package fund
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/koron/funddb/internal/dataobj"
+)
+
+func testRecords(funds ...dataobj.Fund) []fundRecord {
+	records := make([]fundRecord, len(funds))
+	for i, f := range funds {
+		records[i] = fundRecord{Fund: f, File: "funds.tsv", Line: i + 1}
+	}
+	return records
+}
+
+func TestMakePlan(t *testing.T) {
+	db := newTestDB(t)
+	engine := db.open(t)
+	for _, f := range []dataobj.Fund{
+		{ID: "A", Name: "Fund A", URL: "https://example.com/a", FetchID: "test:a"},
+		{ID: "B", Name: "Fund B", URL: "https://example.com/b", FetchID: "test:b"},
+		{ID: "C", Name: "Fund C", URL: "https://example.com/c", FetchID: "test:c"},
+	} {
+		if _, err := engine.Insert(&f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type result struct {
+		id     string
+		action action
+		reason string
+	}
+	for _, tc := range []struct {
+		name    string
+		records []fundRecord
+		prune   bool
+		want    []result
+	}{
+		{
+			name: "insert, update and unchanged",
+			records: testRecords(
+				dataobj.Fund{ID: "A", Name: "Fund A", URL: "https://example.com/a", FetchID: "test:a"},
+				dataobj.Fund{ID: "B", Name: "Fund B2", URL: "https://example.com/b", FetchID: "test:b"},
+				dataobj.Fund{ID: "D", Name: "Fund D", URL: "https://example.com/d"},
+			),
+			want: []result{{"A", actUnchanged, ""}, {"B", actUpdate, ""}, {"D", actInsert, ""}},
+		},
+		{
+			name: "empty fetch_id keeps the current one",
+			records: testRecords(
+				dataobj.Fund{ID: "A", Name: "Fund A", URL: "https://example.com/a"},
+			),
+			want: []result{{"A", actUnchanged, ""}},
+		},
+		{
+			name: "duplicated IDs",
+			records: testRecords(
+				dataobj.Fund{ID: "D", Name: "Fund D", URL: "https://example.com/d"},
+				dataobj.Fund{ID: "D", Name: "Fund D2", URL: "https://example.com/d2"},
+			),
+			want: []result{{"D", actInsert, ""}, {"D", actConflict, "duplicated id, which is at funds.tsv:1"}},
+		},
+		{
+			name: "value used by a fund out of files",
+			records: testRecords(
+				dataobj.Fund{ID: "D", Name: "Fund C", URL: "https://example.com/d"},
+			),
+			want: []result{{"D", actConflict, `name "Fund C" is used by fund C`}},
+		},
+		{
+			name: "swap values between funds",
+			records: testRecords(
+				dataobj.Fund{ID: "A", Name: "Fund B", URL: "https://example.com/a", FetchID: "test:b"},
+				dataobj.Fund{ID: "B", Name: "Fund A", URL: "https://example.com/b", FetchID: "test:a"},
+			),
+			want: []result{{"A", actUpdate, ""}, {"B", actUpdate, ""}},
+		},
+		{
+			name: "prune",
+			records: testRecords(
+				dataobj.Fund{ID: "A", Name: "Fund A", URL: "https://example.com/a"},
+				dataobj.Fund{ID: "D", Name: "Fund C", URL: "https://example.com/c", FetchID: "test:c"},
+			),
+			prune: true,
+			want:  []result{{"A", actUnchanged, ""}, {"D", actInsert, ""}, {"B", actDelete, ""}, {"C", actDelete, ""}},
+		},
+	} {
+		session := engine.NewSession()
+		plan, err := makePlan(session, tc.records, tc.prune)
+		session.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		got := make([]result, len(plan))
+		for i, item := range plan {
+			id := item.Record.ID
+			if item.Action == actDelete {
+				id = item.Old.ID
+			}
+			got[i] = result{id, item.Action, item.Reason}
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: unexpected plan:\nwant=%+v\ngot=%+v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	plan := []planItem{
+		{Action: actUpdate, Record: testRecords(dataobj.Fund{ID: "A"})[0], Diffs: []fieldDiff{{Field: "name", Old: "Fund A", New: "Fund A2"}}},
+		{Action: actConflict, Record: testRecords(dataobj.Fund{}, dataobj.Fund{ID: "B"})[1], Reason: "duplicated id, which is at funds.tsv:1"},
+		{Action: actDelete, Old: dataobj.Fund{ID: "C", Name: "Fund C"}},
+	}
+	var buf bytes.Buffer
+	printPlan(&buf, plan)
+	printSummary(&buf, plan)
+	want := `update    A (funds.tsv:1)
+    name: "Fund A" -> "Fund A2"
+conflict  B (funds.tsv:2): duplicated id, which is at funds.tsv:1
+delete    C (Fund C)
+insert: 0, update: 1, unchanged: 0, delete: 1, conflict: 1
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected output:\nwant=%s\ngot=%s", want, got)
+	}
+}
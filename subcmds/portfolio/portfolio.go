@@ -0,0 +1,96 @@
+package portfolio
+
+import (
+	"context"
+	"flag"
+	"math"
+	"os"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
+	"github.com/koron/funddb/internal/portfolio"
+)
+
+var Value = subcmd.DefineCommand("value", "show holdings, cost basis and unrealized gain", func(ctx context.Context, args []string) error {
+	var (
+		date    = dataobj.DateFromTime(time.Now())
+		account string
+		format  string
+	)
+	ac, _, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&date, "date", "evaluate holdings on the date (YYYY-MM-DD, default: today)")
+		fs.StringVar(&account, "account", "", "filter by account ID")
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	session := ac.ORM.Where("date <= ?", date)
+	if account != "" {
+		session.And("account_id = ?", account)
+	}
+	var txns []dataobj.Transaction
+	if err := session.OrderBy("date, id").Find(&txns); err != nil {
+		return err
+	}
+	holdings, err := portfolio.Holdings(txns, date)
+	if err != nil {
+		return err
+	}
+
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader([]string{"account", "fund", "units", "price_date", "price", "value", "cost", "gain", "gain_pct", "realized"}); err != nil {
+		return err
+	}
+	var totalValue, totalCost, totalRealized int64
+	for _, h := range holdings {
+		if h.Units == 0 && h.Realized == 0 {
+			continue
+		}
+		// the latest price on or before the date.
+		var p dataobj.Price
+		has, err := ac.ORM.Where("id = ? AND date <= ?", h.FundID, date).Desc("date").Get(&p)
+		if err != nil {
+			return err
+		}
+		row := []any{h.AccountID, h.FundID, h.Units, nil, nil, nil, h.Cost, nil, nil, h.Realized}
+		if has {
+			v := h.Value(p.Value)
+			row[3], row[4], row[5] = p.Date, p.Value, v
+			row[7] = v - h.Cost
+			if h.Cost != 0 {
+				row[8] = math.Round(float64(v-h.Cost)/float64(h.Cost)*10000) / 100
+			}
+			totalValue += v
+		}
+		totalCost += h.Cost
+		totalRealized += h.Realized
+		if err := w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	total := []any{"TOTAL", nil, nil, nil, nil, totalValue, totalCost, totalValue - totalCost, nil, totalRealized}
+	if totalCost != 0 {
+		total[8] = math.Round(float64(totalValue-totalCost)/float64(totalCost)*10000) / 100
+	}
+	if err := w.WriteRow(total); err != nil {
+		return err
+	}
+	return w.Flush()
+})
+
+var Set = subcmd.DefineSet("portfolio", "operate portfolio",
+	Value,
+)
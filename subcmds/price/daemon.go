@@ -0,0 +1,94 @@
+package price
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/calendar"
+	"github.com/koron/funddb/internal/fetcher"
+)
+
+var Daemon = subcmd.DefineCommand("daemon", "fetch latest prices on a schedule", func(ctx context.Context, args []string) error {
+	var (
+		days     string
+		at       string
+		tz       string
+		verbose  bool
+		now      bool
+		holidays bool
+		parallel int
+		interval time.Duration
+		rp       adapter.RetryPolicy
+		d        fetcher.Daemon
+	)
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&days, "days", "mon-fri", "days of week to fetch, like \"mon-fri\" or \"mon,wed,fri\"")
+		fs.StringVar(&at, "at", "18:00", "time of day to start fetching (HH:MM)")
+		fs.StringVar(&tz, "tz", "Japan", "time zone of the schedule")
+		fs.BoolVar(&verbose, "verbose", false, "verbose messages")
+		fs.BoolVar(&now, "now", false, "run a batch immediately before waiting the schedule")
+		fs.BoolVar(&holidays, "skip-holidays", true, "skip Japanese holidays and year-end closures")
+		fs.DurationVar(&d.PollInterval, "poll-interval", 30*time.Minute, "interval to re-poll funds which got no new prices")
+		fs.DurationVar(&d.PollDuration, "poll-duration", 6*time.Hour, "duration to keep re-polling in a batch")
+		fs.IntVar(&parallel, "parallel", 4, "number of concurrent fetches")
+		fs.DurationVar(&interval, "interval", 500*time.Millisecond, "minimum interval between requests for a scheme")
+		appcore.RetryFlags(fs, &rp)
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+
+	d.Schedule.Weekdays, err = fetcher.ParseWeekdays(days)
+	if err != nil {
+		return err
+	}
+	d.Schedule.At, err = fetcher.ParseTimeOfDay(at)
+	if err != nil {
+		return err
+	}
+	d.Schedule.Location, err = time.LoadLocation(tz)
+	if err != nil {
+		return err
+	}
+	if holidays {
+		d.Schedule.Calendar = calendar.Default()
+	}
+	d.ORM = ac.ORM
+	d.IDs = ids
+	d.Fetcher = &fetcher.Fetcher{
+		Parallel: parallel,
+		Interval: interval,
+		Retry:    rp,
+		Progress: func(r fetcher.Result) {
+			if r.Err != nil {
+				log.Printf("failed to fetch ID=%s: %v", r.FetchID, r.Err)
+				return
+			}
+			if verbose {
+				log.Printf("fetched latest price for %s in %s", r.FetchID, r.Latency)
+			}
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if now {
+		err := d.RunBatch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return d.Run(ctx)
+})
@@ -0,0 +1,63 @@
+package price
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
+)
+
+var Distributions = subcmd.DefineCommand("distributions", "list distributions of funds", func(ctx context.Context, args []string) error {
+	var (
+		from   dataobj.Date
+		to     dataobj.Date
+		format string
+	)
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&from, "from", "first ex-date of distributions (YYYY-MM-DD)")
+		fs.Var(&to, "to", "last ex-date of distributions (YYYY-MM-DD)")
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	session := ac.ORM.NewSession()
+	defer session.Close()
+	if len(ids) > 0 {
+		session.In("id", ids)
+	}
+	if !from.IsZero() {
+		session.And("ex_date >= ?", from)
+	}
+	if !to.IsZero() {
+		session.And("ex_date <= ?", to)
+	}
+	var list []dataobj.Distribution
+	if err := session.OrderBy("id, ex_date").Find(&list); err != nil {
+		return err
+	}
+
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader([]string{"id", "ex_date", "amount"}); err != nil {
+		return err
+	}
+	for _, d := range list {
+		if err := w.WriteRow([]any{d.ID, d.ExDate, d.Amount}); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+})
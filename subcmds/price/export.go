@@ -0,0 +1,325 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
+	"github.com/koron/funddb/internal/parquet"
+	"xorm.io/xorm"
+)
+
+// exportFormat is a format of exported prices, which is a flag.Value.
+type exportFormat string
+
+const (
+	exportCSV     exportFormat = "csv"
+	exportJSONL   exportFormat = "jsonl"
+	exportParquet exportFormat = "parquet"
+)
+
+var exportFormats = []exportFormat{exportCSV, exportJSONL, exportParquet}
+
+func (f *exportFormat) String() string {
+	return string(*f)
+}
+
+func (f *exportFormat) Set(s string) error {
+	if !slices.Contains(exportFormats, exportFormat(s)) {
+		return fmt.Errorf("unknown format %q, available formats are: csv|jsonl|parquet", s)
+	}
+	*f = exportFormat(s)
+	return nil
+}
+
+// exportColumn is a column of exported prices. Values of optional columns
+// may be nil.
+type exportColumn struct {
+	name     string
+	typ      parquet.Type
+	optional bool
+}
+
+// rowWriter writes exported rows, and closes at the end.
+type rowWriter interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+type outputWriter struct {
+	output.Writer
+}
+
+func (w outputWriter) Close() error {
+	return w.Flush()
+}
+
+// parquetWriter converts dates to time.Time for the parquet.Writer.
+type parquetWriter struct {
+	*parquet.Writer
+}
+
+func (w parquetWriter) WriteRow(values []any) error {
+	for i, v := range values {
+		if d, ok := v.(dataobj.Date); ok {
+			values[i] = d.Time(time.UTC)
+		}
+	}
+	return w.Writer.WriteRow(values)
+}
+
+func newRowWriter(w io.Writer, f exportFormat, columns []exportColumn) (rowWriter, error) {
+	if f == exportParquet {
+		pcols := make([]parquet.Column, len(columns))
+		for i, c := range columns {
+			pcols[i] = parquet.Column{Name: c.name, Type: c.typ, Optional: c.optional}
+		}
+		pw, err := parquet.NewWriter(w, pcols, 0)
+		if err != nil {
+			return nil, err
+		}
+		return parquetWriter{pw}, nil
+	}
+	ow, err := output.NewWriter(w, output.Format(f))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	if err := ow.WriteHeader(names); err != nil {
+		return nil, err
+	}
+	return outputWriter{ow}, nil
+}
+
+// exportQuery is a condition of exported prices.
+type exportQuery struct {
+	ids  []string
+	from dataobj.Date
+	to   dataobj.Date
+}
+
+func (q exportQuery) session(orm *xorm.Engine) *xorm.Session {
+	session := orm.NewSession()
+	if len(q.ids) > 0 {
+		session.In("id", q.ids)
+	}
+	if !q.from.IsZero() {
+		session.And("date >= ?", q.from)
+	}
+	if !q.to.IsZero() {
+		session.And("date <= ?", q.to)
+	}
+	return session
+}
+
+// fundIDs returns IDs of funds to export: specified IDs, or all funds which
+// have prices in the period.
+func (q exportQuery) fundIDs(orm *xorm.Engine) ([]string, error) {
+	if len(q.ids) > 0 {
+		return q.ids, nil
+	}
+	session := q.session(orm)
+	defer session.Close()
+	var ids []string
+	if err := session.Table(&dataobj.Price{}).Distinct("id").Asc("id").Find(&ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// eachPrice iterates prices one by one in an order, without loading all of
+// them into the memory.
+func (q exportQuery) eachPrice(orm *xorm.Engine, orderBy string, fn func(p *dataobj.Price) error) error {
+	session := q.session(orm)
+	defer session.Close()
+	rows, err := session.OrderBy(orderBy).Rows(&dataobj.Price{})
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p dataobj.Price
+		if err := rows.Scan(&p); err != nil {
+			return err
+		}
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func exportLong(orm *xorm.Engine, q exportQuery, w io.Writer, f exportFormat) error {
+	rw, err := newRowWriter(w, f, []exportColumn{
+		{name: "id", typ: parquet.String},
+		{name: "date", typ: parquet.Date},
+		{name: "value", typ: parquet.Int64},
+		{name: "net_assets", typ: parquet.Int64, optional: true},
+	})
+	if err != nil {
+		return err
+	}
+	err = q.eachPrice(orm, "id, date", func(p *dataobj.Price) error {
+		row := []any{p.ID, p.Date, p.Value, nil}
+		if p.NetAssets > 0 {
+			row[3] = p.NetAssets
+		}
+		return rw.WriteRow(row)
+	})
+	if err != nil {
+		return err
+	}
+	return rw.Close()
+}
+
+// pivot composes rows of the wide layout: a row for each date, with a value
+// for each fund.
+type pivot struct {
+	ffill bool
+	limit int // maximum number of filled rows in a run, 0 means no limit
+
+	date   dataobj.Date
+	values []any
+	last   []any
+	filled []int
+}
+
+func newPivot(n int, ffill bool, limit int) *pivot {
+	return &pivot{
+		ffill:  ffill,
+		limit:  limit,
+		values: make([]any, n),
+		last:   make([]any, n),
+		filled: make([]int, n),
+	}
+}
+
+// row returns a row of the current date, and resets values for the next.
+func (pv *pivot) row() []any {
+	row := make([]any, 0, len(pv.values)+1)
+	row = append(row, pv.date)
+	for i, v := range pv.values {
+		switch {
+		case v != nil:
+			pv.last[i] = v
+			pv.filled[i] = 0
+		case pv.ffill && pv.last[i] != nil && (pv.limit <= 0 || pv.filled[i] < pv.limit):
+			v = pv.last[i]
+			pv.filled[i]++
+		}
+		row = append(row, v)
+		pv.values[i] = nil
+	}
+	return row
+}
+
+func exportWide(orm *xorm.Engine, q exportQuery, w io.Writer, f exportFormat, ffill bool, limit int) error {
+	ids, err := q.fundIDs(orm)
+	if err != nil {
+		return err
+	}
+	columns := []exportColumn{{name: "date", typ: parquet.Date}}
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		columns = append(columns, exportColumn{name: id, typ: parquet.Int64, optional: true})
+		index[id] = i
+	}
+	pv := newPivot(len(ids), ffill, limit)
+	// seed forward-fill with the last prices before the period.
+	if ffill && !q.from.IsZero() {
+		for i, id := range ids {
+			var p dataobj.Price
+			has, err := orm.Where("id = ? AND date < ?", id, q.from).Desc("date").Get(&p)
+			if err != nil {
+				return err
+			}
+			if has {
+				pv.last[i] = p.Value
+			}
+		}
+	}
+
+	rw, err := newRowWriter(w, f, columns)
+	if err != nil {
+		return err
+	}
+	q.ids = ids
+	started := false
+	err = q.eachPrice(orm, "date, id", func(p *dataobj.Price) error {
+		if started && p.Date != pv.date {
+			if err := rw.WriteRow(pv.row()); err != nil {
+				return err
+			}
+		}
+		started = true
+		pv.date = p.Date
+		pv.values[index[p.ID]] = p.Value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if started {
+		if err := rw.WriteRow(pv.row()); err != nil {
+			return err
+		}
+	}
+	return rw.Close()
+}
+
+var Export = subcmd.DefineCommand("export", "export prices for analysis tools", func(ctx context.Context, args []string) error {
+	var (
+		from   dataobj.Date
+		to     dataobj.Date
+		ids    string
+		format = exportCSV
+		layout string
+		ffill  bool
+		limit  int
+	)
+	ac, rest, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&from, "from", "first date of prices (YYYY-MM-DD)")
+		fs.Var(&to, "to", "last date of prices (YYYY-MM-DD)")
+		fs.StringVar(&ids, "ids", "", "comma separated IDs of funds to export (default: all funds)")
+		fs.Var(&format, "format", "output format: csv|jsonl|parquet")
+		fs.StringVar(&layout, "layout", "long", "layout of rows: long (a row for each fund and date) or wide (a column for each fund)")
+		fs.BoolVar(&ffill, "ffill", false, "fill missing prices with the last ones in the wide layout")
+		fs.IntVar(&limit, "ffill-limit", 0, "maximum number of consecutive rows to fill, 0 means no limit")
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	q := exportQuery{from: from, to: to}
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			q.ids = append(q.ids, id)
+		}
+	}
+	q.ids = append(q.ids, rest...)
+
+	switch layout {
+	case "long":
+		if ffill {
+			return errors.New("-ffill is available only for the wide layout")
+		}
+		return exportLong(ac.ORM, q, os.Stdout, format)
+	case "wide":
+		return exportWide(ac.ORM, q, os.Stdout, format, ffill, limit)
+	default:
+		return fmt.Errorf("unknown layout %q, available layouts are: long|wide", layout)
+	}
+})
@@ -0,0 +1,131 @@
+package price
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/google/go-cmp/cmp"
+	"github.com/koron/funddb/internal/dataobj"
+	"xorm.io/xorm"
+)
+
+func insertExportPrices(t *testing.T, engine *xorm.Engine) {
+	t.Helper()
+	for _, p := range []dataobj.Price{
+		{ID: "F1", Date: dataobj.NewDate(2024, 6, 24), Value: 12345, NetAssets: 45_678_000_000},
+		{ID: "F1", Date: dataobj.NewDate(2024, 6, 25), Value: 12400, NetAssets: 45_700_000_000},
+		{ID: "F2", Date: dataobj.NewDate(2024, 6, 24), Value: 9800},
+		{ID: "F2", Date: dataobj.NewDate(2024, 6, 26), Value: 9850},
+	} {
+		if _, err := engine.Insert(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readParquet reads an exported Parquet file with the reader of Apache Arrow,
+// and returns logical types of columns, the schema and values of columns.
+func readParquet(t *testing.T, b []byte) ([]string, *arrow.Schema, [][]string) {
+	t.Helper()
+	r, err := file.NewParquetReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	fr, err := pqarrow.NewFileReader(r, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := fr.ReadTable(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Release()
+
+	schema := r.MetaData().Schema
+	var types []string
+	for i := range schema.NumColumns() {
+		types = append(types, schema.Column(i).LogicalType().String())
+	}
+	// drop metadata of fields, which have field IDs.
+	fields := tbl.Schema().Fields()
+	for i := range fields {
+		fields[i].Metadata = arrow.Metadata{}
+	}
+	columns := make([][]string, tbl.NumCols())
+	for i := range columns {
+		for _, chunk := range tbl.Column(i).Data().Chunks() {
+			for j := range chunk.Len() {
+				columns[i] = append(columns[i], chunk.ValueStr(j))
+			}
+		}
+	}
+	return types, arrow.NewSchema(fields, nil), columns
+}
+
+func TestExportLongParquet(t *testing.T) {
+	engine := newMemoryEngine(t)
+	insertExportPrices(t, engine)
+	var buf bytes.Buffer
+	if err := exportLong(engine, exportQuery{}, &buf, exportParquet); err != nil {
+		t.Fatal(err)
+	}
+	types, schema, columns := readParquet(t, buf.Bytes())
+
+	if d := cmp.Diff([]string{"String", "Date", "None", "None"}, types); d != "" {
+		t.Errorf("unmatch logical types: -want +got\n%s", d)
+	}
+	wantSchema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.BinaryTypes.String},
+		{Name: "date", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "net_assets", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	if !schema.Equal(wantSchema) {
+		t.Errorf("unmatch schema:\nwant=%s\ngot=%s", wantSchema, schema)
+	}
+	want := [][]string{
+		{"F1", "F1", "F2", "F2"},
+		{"2024-06-24", "2024-06-25", "2024-06-24", "2024-06-26"},
+		{"12345", "12400", "9800", "9850"},
+		{"45678000000", "45700000000", "(null)", "(null)"},
+	}
+	if d := cmp.Diff(want, columns); d != "" {
+		t.Errorf("unmatch columns: -want +got\n%s", d)
+	}
+}
+
+func TestExportWideParquet(t *testing.T) {
+	engine := newMemoryEngine(t)
+	insertExportPrices(t, engine)
+	var buf bytes.Buffer
+	if err := exportWide(engine, exportQuery{}, &buf, exportParquet, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	types, schema, columns := readParquet(t, buf.Bytes())
+
+	if d := cmp.Diff([]string{"Date", "None", "None"}, types); d != "" {
+		t.Errorf("unmatch logical types: -want +got\n%s", d)
+	}
+	wantSchema := arrow.NewSchema([]arrow.Field{
+		{Name: "date", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "F1", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "F2", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	if !schema.Equal(wantSchema) {
+		t.Errorf("unmatch schema:\nwant=%s\ngot=%s", wantSchema, schema)
+	}
+	want := [][]string{
+		{"2024-06-24", "2024-06-25", "2024-06-26"},
+		{"12345", "12400", "(null)"},
+		{"9800", "(null)", "9850"},
+	}
+	if d := cmp.Diff(want, columns); d != "" {
+		t.Errorf("unmatch columns: -want +got\n%s", d)
+	}
+}
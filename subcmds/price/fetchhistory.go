@@ -0,0 +1,106 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/fetcher"
+	"github.com/koron/funddb/internal/fundprice"
+	"github.com/koron/funddb/internal/xormhelper"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+var FetchHistory = subcmd.DefineCommand("fetchhistory", "fetch history of prices and put into DB", func(ctx context.Context, args []string) error {
+	var (
+		from    dataobj.Date
+		to      dataobj.Date
+		verbose bool
+	)
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&from, "from", "start date of history (YYYY-MM-DD, default: a year before -to)")
+		fs.Var(&to, "to", "end date of history (YYYY-MM-DD, default: today)")
+		fs.BoolVar(&verbose, "verbose", false, "verbose messages")
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if len(ids) == 0 {
+		return errors.New("require one or more fund IDs")
+	}
+	loc, err := time.LoadLocation("Japan")
+	if err != nil {
+		return err
+	}
+	if to.IsZero() {
+		to = dataobj.DateFromTime(time.Now().In(loc))
+	}
+	if from.IsZero() {
+		from = dataobj.DateFromTime(to.Time(loc).AddDate(-1, 0, 0))
+	}
+	if from.Compare(to) > 0 {
+		return fmt.Errorf("-from (%s) is after -to (%s)", from, to)
+	}
+
+	// fetch histories before starting a transaction.
+	var (
+		prices  []dataobj.Price
+		sources []fundprice.Price
+	)
+	for _, id := range ids {
+		var fund dataobj.Fund
+		has, err := ac.ORM.ID(id).Get(&fund)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return fmt.Errorf("no funds found for ID=%s", id)
+		}
+		if fund.FetchID == "" {
+			return fmt.Errorf("no fetch ID for fund ID=%s", id)
+		}
+		list, err := adapter.FetchHistory(ctx, adapter.ExpandFetchID(fund.FetchID, fund.ID), from.Time(loc), to.Time(loc))
+		if err != nil {
+			return fmt.Errorf("failed to fetch history of ID=%s: %w", fund.FetchID, err)
+		}
+		if verbose {
+			log.Printf("fetched %d prices for %s", len(list), fund.FetchID)
+		}
+		if len(list) > 0 {
+			if err := fetcher.CheckAssociationCode(fund.ID, list[0]); err != nil {
+				log.Printf("WARN: %s", err)
+			}
+		}
+		for _, p := range list {
+			prices = append(prices, dataobj.Price{
+				ID:        fund.ID,
+				Date:      dataobj.DateFromTime(p.Date()),
+				Value:     p.Price(),
+				NetAssets: p.NetAssets(),
+			})
+			sources = append(sources, p)
+		}
+	}
+
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		for i, pd := range prices {
+			pk := schemas.PK{pd.ID, pd.Date}
+			if err := xormhelper.UpsertOne(session, pk, pd); err != nil {
+				return err
+			}
+			if err := fetcher.StoreMetrics(session, pd.ID, sources[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+})
@@ -0,0 +1,113 @@
+package price
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"xorm.io/xorm"
+)
+
+// failingFund is a fund which failed to fetch in a row.
+type failingFund struct {
+	FundID    string
+	FetchID   string
+	Failures  int
+	Since     time.Time
+	LastError string
+}
+
+// queryFailingFunds finds funds which have failed at least n times in a row
+// since the last success.
+const queryFailingFunds = `SELECT
+	r.fund_id AS fund_id,
+	(SELECT fetch_id FROM fetch_results WHERE fund_id = r.fund_id ORDER BY run_id DESC LIMIT 1) AS fetch_id,
+	COUNT(*) AS failures,
+	MIN(runs.started_at) AS since,
+	(SELECT error FROM fetch_results WHERE fund_id = r.fund_id ORDER BY run_id DESC LIMIT 1) AS last_error
+FROM fetch_results AS r
+	JOIN fetch_runs AS runs ON runs.id = r.run_id
+WHERE r.status = ?
+	AND r.run_id > COALESCE((SELECT MAX(run_id) FROM fetch_results WHERE fund_id = r.fund_id AND status = ?), 0)
+GROUP BY r.fund_id
+HAVING COUNT(*) >= ?
+ORDER BY failures DESC, r.fund_id`
+
+// findFailingFunds finds funds which have failed at least n times in a row.
+func findFailingFunds(orm *xorm.Engine, n int) ([]failingFund, error) {
+	var list []failingFund
+	if err := orm.SQL(queryFailingFunds, dataobj.FetchStatusError, dataobj.FetchStatusOK, n).Find(&list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+var FetchLog = subcmd.DefineCommand("fetchlog", "show recent fetch runs and funds failed in a row", func(ctx context.Context, args []string) error {
+	var (
+		runs     int
+		failures int
+		runID    int64
+	)
+	ac, _, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.IntVar(&runs, "runs", 10, "number of recent runs to show")
+		fs.IntVar(&failures, "failures", 3, "show funds which failed at least this times in a row")
+		fs.Int64Var(&runID, "run", 0, "show results of a run by ID")
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	defer w.Flush()
+
+	if runID != 0 {
+		var results []dataobj.FetchResult
+		if err := ac.ORM.Where("run_id = ?", runID).OrderBy("fund_id").Find(&results); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "FUND\tFETCH ID\tSTATUS\tLATENCY\tDATE\tPRICE\tERROR")
+		for _, r := range results {
+			latency := time.Duration(r.LatencyMsec) * time.Millisecond
+			date, price := "-", "-"
+			if r.Status == dataobj.FetchStatusOK {
+				date, price = r.Date.String(), fmt.Sprint(r.Price)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.FundID, r.FetchID, r.Status, latency, date, price, r.Error)
+		}
+		return nil
+	}
+
+	var runList []dataobj.FetchRun
+	if err := ac.ORM.Desc("id").Limit(runs).Find(&runList); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "RUN\tSTARTED\tDURATION\tTOTAL\tFAILED")
+	for _, r := range runList {
+		d := r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\n", r.ID, r.StartedAt.Format(time.DateTime), d, r.Total, r.Failed)
+	}
+
+	if failures <= 0 {
+		return nil
+	}
+	failing, err := findFailingFunds(ac.ORM, failures)
+	if err != nil {
+		return err
+	}
+	if len(failing) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "FUND\tFETCH ID\tFAILURES\tSINCE\tLAST ERROR")
+	for _, f := range failing {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", f.FundID, f.FetchID, f.Failures, f.Since.Format(time.DateTime), f.LastError)
+	}
+	return nil
+})
@@ -0,0 +1,91 @@
+package price
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/koron/funddb/internal/dataobj"
+	"xorm.io/xorm"
+)
+
+// newMemoryEngine opens a migrated in-memory database. It is limited to a
+// connection, as each connection has its own in-memory database.
+func newMemoryEngine(t *testing.T) *xorm.Engine {
+	t.Helper()
+	engine, err := dataobj.NewEngine(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.SetMaxOpenConns(1)
+	t.Cleanup(func() { engine.Close() })
+	if err := dataobj.Migrate(engine, false); err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestFindFailingFunds(t *testing.T) {
+	engine := newMemoryEngine(t)
+	start := time.Date(2024, 6, 24, 18, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		run := dataobj.FetchRun{ID: int64(i + 1), StartedAt: start.AddDate(0, 0, i), FinishedAt: start.AddDate(0, 0, i).Add(time.Minute)}
+		if _, err := engine.Insert(&run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// statuses of funds for runs #1 to #4: "A" failed after a success, "B"
+	// never succeeded and changed its fetch ID, "C" failed twice after a
+	// success, and "D" never failed.
+	for fundID, statuses := range map[string]string{
+		"A": "oxxx",
+		"B": "xxxx",
+		"C": "xoxx",
+		"D": "oooo",
+	} {
+		for i, c := range statuses {
+			r := dataobj.FetchResult{RunID: int64(i + 1), FundID: fundID, FetchID: "test:" + fundID, Status: dataobj.FetchStatusOK}
+			if c == 'x' {
+				r.Status = dataobj.FetchStatusError
+				r.Error = fmt.Sprintf("error #%d", i+1)
+			}
+			if fundID == "B" && i == 3 {
+				r.FetchID = "test:b2"
+			}
+			if _, err := engine.Insert(&r); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		n    int
+		want []failingFund
+	}{
+		{3, []failingFund{
+			{FundID: "B", FetchID: "test:b2", Failures: 4, Since: start, LastError: "error #4"},
+			{FundID: "A", FetchID: "test:A", Failures: 3, Since: start.AddDate(0, 0, 1), LastError: "error #4"},
+		}},
+		{2, []failingFund{
+			{FundID: "B", FetchID: "test:b2", Failures: 4, Since: start, LastError: "error #4"},
+			{FundID: "A", FetchID: "test:A", Failures: 3, Since: start.AddDate(0, 0, 1), LastError: "error #4"},
+			{FundID: "C", FetchID: "test:C", Failures: 2, Since: start.AddDate(0, 0, 2), LastError: "error #4"},
+		}},
+		{5, nil},
+	} {
+		got, err := findFailingFunds(engine, tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("unexpected failing funds for %d: want=%+v got=%+v", tc.n, tc.want, got)
+			continue
+		}
+		for i, want := range tc.want {
+			g := got[i]
+			if g.FundID != want.FundID || g.FetchID != want.FetchID || g.Failures != want.Failures || !g.Since.Equal(want.Since) || g.LastError != want.LastError {
+				t.Errorf("unmatch failing fund #%d for %d: want=%+v got=%+v", i, tc.n, want, g)
+			}
+		}
+	}
+}
@@ -5,17 +5,23 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"log"
 
 	"github.com/k0kubun/pp/v3"
 	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/adapter"
 	"github.com/koron/funddb/internal/appcore"
 	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/fetcher"
 	"github.com/koron/funddb/internal/xormhelper"
 	"xorm.io/xorm"
 )
 
 var FetchTest = subcmd.DefineCommand("fetchtest", "test: fetch price data and print", func(ctx context.Context, args []string) error {
-	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {})
+	var rp adapter.RetryPolicy
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		appcore.RetryFlags(fs, &rp)
+	})
 	if err != nil {
 		return err
 	}
@@ -34,10 +40,13 @@ var FetchTest = subcmd.DefineCommand("fetchtest", "test: fetch price data and pr
 			if !has {
 				return fmt.Errorf("no funds found for ID=%s", id)
 			}
-			p, err := fetchPrice(ctx, fund.FetchID)
+			p, err := rp.Fetch(ctx, adapter.ExpandFetchID(fund.FetchID, fund.ID))
 			if err != nil {
 				return err
 			}
+			if err := fetcher.CheckAssociationCode(fund.ID, p); err != nil {
+				log.Printf("WARN: %s", err)
+			}
 			pp.Print(p)
 		}
 		return nil
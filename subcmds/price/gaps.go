@@ -0,0 +1,170 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/calendar"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
+	"xorm.io/xorm"
+)
+
+// fundCalendar returns the business day calendar for a fund, with its
+// fund specific holidays.
+func fundCalendar(orm *xorm.Engine, id string) (*calendar.Calendar, error) {
+	var list []dataobj.FundHoliday
+	if err := orm.Where("fund_id = ?", id).Find(&list); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return calendar.Default(), nil
+	}
+	extra := make(map[dataobj.Date]string, len(list))
+	for _, h := range list {
+		extra[h.Date] = h.Note
+	}
+	return calendar.Default().WithHolidays(extra), nil
+}
+
+// warnCoverage warns when a period is out of the bundled holiday table.
+func warnCoverage(cal *calendar.Calendar, from, to dataobj.Date) {
+	if !cal.Covers(from) || !cal.Covers(to) {
+		log.Printf("WARN: holidays are unknown for some days in %s to %s, only weekends and year-end closures are considered", from, to)
+	}
+}
+
+var Gaps = subcmd.DefineCommand("gaps", "list business days which miss prices", func(ctx context.Context, args []string) error {
+	var (
+		from   dataobj.Date
+		to     dataobj.Date
+		format string
+	)
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&from, "from", "first date to check (YYYY-MM-DD, default: the first price)")
+		fs.Var(&to, "to", "last date to check (YYYY-MM-DD, default: the last price)")
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if len(ids) == 0 {
+		return errors.New("require one or more fund IDs")
+	}
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	q := dataobj.PriceQuery{From: from, To: to}
+
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader([]string{"id", "date", "weekday"}); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		prices, _, err := q.Find(ac.ORM, id)
+		if err != nil {
+			return err
+		}
+		if len(prices) == 0 {
+			log.Printf("no prices for %s", id)
+			continue
+		}
+		cal, err := fundCalendar(ac.ORM, id)
+		if err != nil {
+			return err
+		}
+		first, last := q.From, q.To
+		if first.IsZero() {
+			first = prices[0].Date
+		}
+		if last.IsZero() {
+			last = prices[len(prices)-1].Date
+		}
+		warnCoverage(cal, first, last)
+		dates := make([]dataobj.Date, len(prices))
+		for i, p := range prices {
+			dates[i] = p.Date
+		}
+		for _, d := range cal.Gaps(dates, first, last) {
+			if err := w.WriteRow([]any{id, d, d.Time(time.UTC).Weekday().String()[:3]}); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+})
+
+var Stale = subcmd.DefineCommand("stale", "list funds whose latest prices are older than N business days", func(ctx context.Context, args []string) error {
+	var (
+		days   int
+		date   = dataobj.DateFromTime(time.Now())
+		format string
+	)
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.IntVar(&days, "days", 1, "allowed age of latest prices in business days")
+		fs.Var(&date, "date", "reference date (YYYY-MM-DD, default: today)")
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	session := ac.ORM.OrderBy("id")
+	if len(ids) > 0 {
+		session.In("id", ids)
+	}
+	var funds []dataobj.Fund
+	if err := session.Find(&funds); err != nil {
+		return err
+	}
+
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader([]string{"id", "name", "latest", "age"}); err != nil {
+		return err
+	}
+	for _, fund := range funds {
+		var p dataobj.Price
+		ok, err := ac.ORM.Where("id = ?", fund.ID).Desc("date").Get(&p)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := w.WriteRow([]any{fund.ID, fund.Name, nil, nil}); err != nil {
+				return err
+			}
+			continue
+		}
+		cal, err := fundCalendar(ac.ORM, fund.ID)
+		if err != nil {
+			return err
+		}
+		warnCoverage(cal, p.Date, date)
+		age := cal.Age(p.Date, date)
+		if age <= days {
+			continue
+		}
+		if err := w.WriteRow([]any{fund.ID, fund.Name, p.Date, age}); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+})
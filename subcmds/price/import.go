@@ -0,0 +1,127 @@
+package price
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/pricecsv"
+	"github.com/koron/funddb/internal/xormhelper"
+	"golang.org/x/text/encoding/japanese"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+func layoutNames() string {
+	names := make([]string, len(pricecsv.Layouts))
+	for i, l := range pricecsv.Layouts {
+		names[i] = fmt.Sprintf("%s (%s)", l.Name, l.Desc)
+	}
+	return strings.Join(names, ", ")
+}
+
+// decodeFile reads a file in an encoding: "auto", "utf-8" or "shift_jis".
+func decodeFile(fname, encoding string) ([]byte, error) {
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(encoding) {
+	case "auto":
+		return pricecsv.Decode(b)
+	case "utf-8", "utf8":
+		return bytes.TrimPrefix(b, []byte("\xef\xbb\xbf")), nil
+	case "shift_jis", "sjis", "cp932":
+		return japanese.ShiftJIS.NewDecoder().Bytes(b)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q, available encodings are: auto|utf-8|shift_jis", encoding)
+	}
+}
+
+var Import = subcmd.DefineCommand("import", "import prices from CSV files of providers", func(ctx context.Context, args []string) error {
+	var (
+		fundID   string
+		layout   string
+		columns  string
+		unit     string
+		encoding string
+	)
+	ac, files, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&fundID, "fund", "", "ID of the fund (required)")
+		fs.StringVar(&layout, "layout", "", "built-in layout of columns: "+layoutNames())
+		fs.StringVar(&columns, "columns", "", "custom mapping of columns, like \"date=1,price=2,net_assets=4\" or \"date=基準日,price=基準価額\"")
+		fs.StringVar(&unit, "net-assets-unit", "", "unit of net assets for custom mapping: 円|千円|百万円|億円")
+		fs.StringVar(&encoding, "encoding", "auto", "encoding of files: auto|utf-8|shift_jis")
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if fundID == "" {
+		return errors.New("-fund is required")
+	}
+	if len(files) == 0 {
+		return errors.New("require one or more CSV files")
+	}
+
+	var l pricecsv.Layout
+	switch {
+	case layout != "" && columns != "":
+		return errors.New("-layout and -columns are exclusive")
+	case layout != "":
+		var ok bool
+		l, ok = pricecsv.LookupLayout(layout)
+		if !ok {
+			return fmt.Errorf("unknown layout %q, available layouts are: %s", layout, layoutNames())
+		}
+	case columns != "":
+		l, err = pricecsv.ParseColumns(columns)
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("-layout or -columns is required")
+	}
+	if unit != "" {
+		n, ok := pricecsv.Units[unit]
+		if !ok {
+			return fmt.Errorf("unknown unit %q, available units are: 円|千円|百万円|億円", unit)
+		}
+		l.NetAssetsUnit = n
+	}
+
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		has, err := session.Exist(&dataobj.Fund{ID: fundID})
+		if err != nil {
+			return err
+		}
+		if !has {
+			return fmt.Errorf("no funds for id:%s", fundID)
+		}
+		for _, fname := range files {
+			b, err := decodeFile(fname, encoding)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fname, err)
+			}
+			res, err := pricecsv.Read(bytes.NewReader(b), fundID, l)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fname, err)
+			}
+			for _, p := range res.Prices {
+				if err := xormhelper.UpsertOne(session, schemas.PK{p.ID, p.Date}, p); err != nil {
+					return fmt.Errorf("%s: %s: %w", fname, p.Date, err)
+				}
+			}
+			log.Printf("%s: imported %d prices, skipped lines %v", fname, len(res.Prices), res.Skipped)
+		}
+		return nil
+	})
+})
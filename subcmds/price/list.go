@@ -0,0 +1,78 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math"
+	"os"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
+)
+
+var priceColumns = []string{"id", "date", "value", "net_assets", "change", "change_pct"}
+
+// priceRow composes a row of a price with its day-over-day change.
+func priceRow(p dataobj.Price, prev *dataobj.Price) []any {
+	row := []any{p.ID, p.Date, p.Value, nil, nil, nil}
+	if p.NetAssets > 0 {
+		row[3] = p.NetAssets
+	}
+	if prev != nil && prev.Value != 0 {
+		change := p.Value - prev.Value
+		row[4] = change
+		row[5] = math.Round(float64(change)/float64(prev.Value)*10000) / 100
+	}
+	return row
+}
+
+var List = subcmd.DefineCommand("list", "list prices of funds", func(ctx context.Context, args []string) error {
+	var (
+		from   dataobj.Date
+		to     dataobj.Date
+		last   int
+		format string
+	)
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&from, "from", "first date of prices (YYYY-MM-DD)")
+		fs.Var(&to, "to", "last date of prices (YYYY-MM-DD)")
+		fs.IntVar(&last, "last", 0, "show only last N prices for each fund")
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if len(ids) == 0 {
+		return errors.New("require one or more fund IDs")
+	}
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	q := dataobj.PriceQuery{From: from, To: to, Last: last}
+
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader(priceColumns); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		prices, prev, err := q.Find(ac.ORM, id)
+		if err != nil {
+			return err
+		}
+		for _, p := range prices {
+			if err := w.WriteRow(priceRow(p, prev)); err != nil {
+				return err
+			}
+			prev = &p
+		}
+	}
+	return w.Flush()
+})
@@ -5,45 +5,16 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"strings"
+	"time"
 
 	"github.com/koron-go/subcmd"
-	"github.com/koron/funddb/internal/adapter/ammufg"
-	"github.com/koron/funddb/internal/adapter/fidelity"
-	"github.com/koron/funddb/internal/adapter/pictet"
-	"github.com/koron/funddb/internal/adapter/tokiomarineam"
+	"github.com/koron/funddb/internal/adapter"
 	"github.com/koron/funddb/internal/appcore"
 	"github.com/koron/funddb/internal/dataobj"
-	"github.com/koron/funddb/internal/fundprice"
-	"github.com/koron/funddb/internal/xormhelper"
+	"github.com/koron/funddb/internal/fetcher"
 	"xorm.io/xorm"
-	"xorm.io/xorm/schemas"
 )
 
-func fetchPrice(ctx context.Context, fetchID string) (fundprice.Price, error) {
-	parts := strings.SplitN(fetchID, ":", 2)
-	if len(parts) < 2 {
-		return nil, fmt.Errorf("invalid fetch ID, required format \"{scheme}:{id}\": %s", fetchID)
-	}
-	scheme, id := parts[0], parts[1]
-	switch scheme {
-	case "fidelity":
-		return fidelity.Get(ctx, id)
-
-	case "ammufg":
-		return ammufg.Get(ctx, ammufg.CodeTypeFund, id)
-
-	case "pictet":
-		return pictet.Get(ctx, id)
-
-	case "tokiomarineam":
-		return tokiomarineam.Get(ctx, id, nil)
-
-	default:
-		return nil, fmt.Errorf("unknown scheme: %s", scheme)
-	}
-}
-
 func upsertPrice(session *xorm.Session, p *dataobj.Price) error {
 	var curr dataobj.Price
 	ok, err := session.Where("id = ? AND date = ?", p.ID, p.Date).Get(&curr)
@@ -78,73 +49,70 @@ func upsertPrice(session *xorm.Session, p *dataobj.Price) error {
 }
 
 var FetchLatest = subcmd.DefineCommand("fetchlatest", "fetch latest price data and put into DB", func(ctx context.Context, args []string) error {
-	var verbose bool
+	var (
+		verbose  bool
+		parallel int
+		interval time.Duration
+		rp       adapter.RetryPolicy
+	)
 	ac, filter, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
 		fs.BoolVar(&verbose, "verbose", false, "verbose messages")
+		fs.IntVar(&parallel, "parallel", 4, "number of concurrent fetches")
+		fs.DurationVar(&interval, "interval", 500*time.Millisecond, "minimum interval between requests for a scheme")
+		appcore.RetryFlags(fs, &rp)
 	})
 	if err != nil {
 		return err
 	}
 	defer ac.Close()
-	const batchSize = 100
-	var ids []any
-	if len(filter) > 0 {
-		ids = make([]any, len(filter))
-		for i, f := range filter {
-			ids[i] = f
-		}
+	targets, err := fetcher.LoadTargets(ac.ORM, filter)
+	if err != nil {
+		return err
 	}
-	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
-		// count target funds.
-		if len(ids) > 0 {
-			session.In("id", ids...)
-		}
-		fundCnt, err := session.Count(&dataobj.Fund{})
-		if err != nil {
-			return err
-		}
-		if fundCnt == 0 {
-			return nil
-		}
-		// fetch latest price with batches.
-		for i := 0; i < int(fundCnt); i += batchSize {
-			session.OrderBy("id").Limit(batchSize, i)
-			if len(ids) > 0 {
-				session.In("id", ids...)
-			}
-			var fundList []dataobj.Fund
-			if err := session.Find(&fundList); err != nil {
-				return err
+	if len(targets) == 0 {
+		return nil
+	}
+	f := &fetcher.Fetcher{
+		Parallel: parallel,
+		Interval: interval,
+		Retry:    rp,
+		Progress: func(r fetcher.Result) {
+			if r.Err != nil {
+				log.Printf("failed to fetch ID=%s: %v", r.FetchID, r.Err)
+				return
 			}
-			for _, fund := range fundList {
-				if fund.FetchID == "" {
-					continue
-				}
-				if verbose {
-					log.Printf("fetch latest price for %s", fund.FetchID)
-				}
-				p, err := fetchPrice(ctx, fund.FetchID)
-				if err != nil {
-					log.Printf("failed to fetch ID=%s: %v", fund.FetchID, err)
-					continue
-				}
-				pd := dataobj.Price{
-					ID:        fund.ID,
-					Date:      dataobj.DateFromTime(p.Date()),
-					Value:     p.Price(),
-					NetAssets: p.NetAssets(),
-				}
-				pk := schemas.PK{pd.ID, pd.Date}
-				if err := xormhelper.UpsertOne(session, pk, pd); err != nil {
-					return err
-				}
+			if verbose {
+				log.Printf("fetched latest price for %s in %s", r.FetchID, r.Latency)
 			}
-		}
-		return nil
-	})
+		},
+	}
+	startedAt := time.Now()
+	results := f.FetchAll(ctx, targets)
+	_, err = fetcher.Store(ac.ORM, startedAt, results)
+	return err
+})
+
+var Schemes = subcmd.DefineCommand("schemes", "list available fetch schemes", func(ctx context.Context, args []string) error {
+	fs := subcmd.FlagSet(ctx)
+	fs.Parse(args)
+	for _, s := range adapter.Schemes() {
+		fmt.Printf("%s\t%s\n", s.Name, s.Desc)
+	}
+	return nil
 })
 
 var Set = subcmd.DefineSet("price", "operate prices",
 	FetchLatest,
+	Daemon,
 	FetchTest,
+	FetchHistory,
+	FetchLog,
+	Import,
+	Export,
+	List,
+	Distributions,
+	Stats,
+	Gaps,
+	Stale,
+	Schemes,
 )
@@ -0,0 +1,164 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
+	"github.com/koron/funddb/internal/stats"
+	"xorm.io/xorm"
+)
+
+// loadSeries loads prices and distributions of a fund as series of price
+// and total return.
+func loadSeries(orm *xorm.Engine, id string, q dataobj.PriceQuery) (price, total stats.Series, err error) {
+	prices, _, err := q.Find(orm, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	price = make(stats.Series, len(prices))
+	for i, p := range prices {
+		price[i] = stats.Point{Date: p.Date.Time(time.UTC), Value: float64(p.Value)}
+	}
+	dists := map[string]float64{}
+	has, err := orm.IsTableExist(&dataobj.Distribution{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if has && len(prices) > 0 {
+		var list []dataobj.Distribution
+		err := orm.Where("id = ? AND ex_date >= ? AND ex_date <= ?", id, prices[0].Date, prices[len(prices)-1].Date).Find(&list)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, d := range list {
+			dists[d.ExDate.String()] += float64(d.Amount)
+		}
+	}
+	return price, stats.TotalReturn(price, dists), nil
+}
+
+// percent converts a ratio to percent rounded at 2 decimal places, or nil
+// when it is not available.
+func percent(v float64, ok bool) any {
+	if !ok {
+		return nil
+	}
+	return math.Round(v*10000) / 100
+}
+
+// round2 rounds v at 2 decimal places, or returns nil when it is not
+// available.
+func round2(v float64, ok bool) any {
+	if !ok {
+		return nil
+	}
+	return math.Round(v*100) / 100
+}
+
+type statsRow struct {
+	metric string
+	fn     func(s stats.Series) any
+}
+
+func statsRows(riskFree float64) []statsRow {
+	period := func(months int) func(s stats.Series) any {
+		return func(s stats.Series) any {
+			return percent(s.PeriodReturn(months))
+		}
+	}
+	return []statsRow{
+		{"return_1m", period(1)},
+		{"return_3m", period(3)},
+		{"return_6m", period(6)},
+		{"return_1y", period(12)},
+		{"return_3y", period(36)},
+		{"return_since_inception", func(s stats.Series) any { return percent(s.Return()) }},
+		{"annualized_return", func(s stats.Series) any { return percent(s.AnnualizedReturn()) }},
+		{"annualized_volatility", func(s stats.Series) any { return percent(s.AnnualizedVolatility()) }},
+		{"sharpe_ratio", func(s stats.Series) any { return round2(s.SharpeRatio(riskFree)) }},
+		{"max_drawdown", func(s stats.Series) any {
+			dd, ok := s.MaxDrawdown()
+			return percent(dd.Ratio, ok)
+		}},
+		{"max_drawdown_peak", func(s stats.Series) any {
+			dd, ok := s.MaxDrawdown()
+			if !ok {
+				return nil
+			}
+			return dd.Peak.Format(time.DateOnly)
+		}},
+		{"max_drawdown_trough", func(s stats.Series) any {
+			dd, ok := s.MaxDrawdown()
+			if !ok {
+				return nil
+			}
+			return dd.Trough.Format(time.DateOnly)
+		}},
+	}
+}
+
+var Stats = subcmd.DefineCommand("stats", "show performance statistics of funds", func(ctx context.Context, args []string) error {
+	var (
+		from     dataobj.Date
+		to       dataobj.Date
+		riskFree float64
+		format   string
+	)
+	ac, ids, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.Var(&from, "from", "first date of prices (YYYY-MM-DD)")
+		fs.Var(&to, "to", "last date of prices (YYYY-MM-DD)")
+		fs.Float64Var(&riskFree, "riskfree", 0, "annual risk free rate in percent for Sharpe ratio")
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if len(ids) == 0 {
+		return errors.New("require one or more fund IDs")
+	}
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	q := dataobj.PriceQuery{From: from, To: to}
+
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	// "price" is based on prices only, "total" reinvests distributions.
+	if err := w.WriteHeader([]string{"id", "metric", "price", "total"}); err != nil {
+		return err
+	}
+	rows := statsRows(riskFree / 100)
+	for _, id := range ids {
+		price, total, err := loadSeries(ac.ORM, id, q)
+		if err != nil {
+			return err
+		}
+		if len(price) == 0 {
+			return errors.New("no prices for fund " + id)
+		}
+		if err := w.WriteRow([]any{id, "first_date", price[0].Date.Format(time.DateOnly), nil}); err != nil {
+			return err
+		}
+		if err := w.WriteRow([]any{id, "last_date", price[len(price)-1].Date.Format(time.DateOnly), nil}); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err := w.WriteRow([]any{id, r.metric, r.fn(price), r.fn(total)}); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+})
@@ -0,0 +1,70 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/adapter"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/fetcher"
+	"github.com/koron/funddb/internal/server"
+)
+
+var Command = subcmd.DefineCommand("serve", "serve funds and prices over HTTP", func(ctx context.Context, args []string) error {
+	var (
+		addr       string
+		allowWrite bool
+		parallel   int
+		interval   time.Duration
+		rp         adapter.RetryPolicy
+	)
+	ac, _, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&addr, "addr", ":8080", "address to listen")
+		fs.BoolVar(&allowWrite, "allow-write", false, "allow write routes like POST /fetch")
+		fs.IntVar(&parallel, "parallel", 4, "number of concurrent fetches")
+		fs.DurationVar(&interval, "interval", 500*time.Millisecond, "minimum interval between requests for a scheme")
+		appcore.RetryFlags(fs, &rp)
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+
+	s := &server.Server{
+		ORM:        ac.ORM,
+		AllowWrite: allowWrite,
+		Fetcher: &fetcher.Fetcher{
+			Parallel: parallel,
+			Interval: interval,
+			Retry:    rp,
+		},
+	}
+	hs := &http.Server{
+		Addr:    addr,
+		Handler: s.Handler(),
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		hs.Shutdown(sctx)
+	}()
+
+	log.Printf("listening on %s (write routes: %t)", addr, allowWrite)
+	err = hs.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+})
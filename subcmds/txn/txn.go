@@ -0,0 +1,227 @@
+package txn
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koron-go/subcmd"
+	"github.com/koron/funddb/internal/appcore"
+	"github.com/koron/funddb/internal/dataobj"
+	"github.com/koron/funddb/internal/output"
+	"github.com/koron/funddb/internal/xormhelper"
+	"xorm.io/xorm"
+)
+
+// parseAmount parses an integer which may have grouping commas.
+func parseAmount(s string) (int64, error) {
+	return strconv.ParseInt(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 10, 64)
+}
+
+// validate checks a transaction, and creates its account when not exist.
+func validate(session *xorm.Session, t *dataobj.Transaction) error {
+	if t.AccountID == "" || t.FundID == "" {
+		return errors.New("account and fund are required")
+	}
+	if t.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	if !slices.Contains(dataobj.TxnTypes, t.Type) {
+		return fmt.Errorf("unknown type %q, available types are: %s", t.Type, strings.Join(dataobj.TxnTypes, ", "))
+	}
+	if t.Units < 0 || t.Amount < 0 {
+		return errors.New("units and amount should not be negative")
+	}
+	has, err := session.Exist(&dataobj.Fund{ID: t.FundID})
+	if err != nil {
+		return err
+	}
+	if !has {
+		return fmt.Errorf("no funds for id:%s", t.FundID)
+	}
+	has, err = session.Exist(&dataobj.Account{ID: t.AccountID})
+	if err != nil {
+		return err
+	}
+	if !has {
+		_, err := session.Insert(&dataobj.Account{ID: t.AccountID, Name: t.AccountID})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insert(session *xorm.Session, t *dataobj.Transaction) error {
+	if err := validate(session, t); err != nil {
+		return err
+	}
+	if t.Note == "" {
+		session.Omit("note")
+	}
+	_, err := session.Insert(t)
+	return err
+}
+
+var Add = subcmd.DefineCommand("add", "add a transaction", func(ctx context.Context, args []string) error {
+	t := dataobj.Transaction{Date: dataobj.DateFromTime(time.Now())}
+	ac, _, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&t.AccountID, "account", "default", "account ID, created when not exist")
+		fs.StringVar(&t.FundID, "fund", "", "fund ID (required)")
+		fs.Var(&t.Date, "date", "date of the transaction (YYYY-MM-DD, default: today)")
+		fs.StringVar(&t.Type, "type", dataobj.TxnBuy, "type of the transaction: "+strings.Join(dataobj.TxnTypes, ", "))
+		fs.Int64Var(&t.Units, "units", 0, "units (口)")
+		fs.Int64Var(&t.Amount, "amount", 0, "amount in yen")
+		fs.StringVar(&t.Note, "note", "", "note")
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		return insert(session, &t)
+	})
+})
+
+// importFile imports transactions from a CSV file with columns: date,
+// account, fund, type, units, amount and optional note. A header row which
+// starts with "date" is skipped.
+func importFile(session *xorm.Session, fname string) (int, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	var n int
+	for {
+		records, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return n, err
+		}
+		line, _ := r.FieldPos(0)
+		if len(records) < 6 {
+			return n, fmt.Errorf("%s:%d: few records, require 6 at least", fname, line)
+		}
+		if strings.EqualFold(strings.TrimSpace(records[0]), "date") {
+			continue
+		}
+		t := dataobj.Transaction{
+			AccountID: strings.TrimSpace(records[1]),
+			FundID:    strings.TrimSpace(records[2]),
+			Type:      strings.ToLower(strings.TrimSpace(records[3])),
+		}
+		if len(records) >= 7 {
+			t.Note = strings.TrimSpace(records[6])
+		}
+		if err := t.Date.Set(strings.TrimSpace(records[0])); err != nil {
+			return n, fmt.Errorf("%s:%d: invalid date: %w", fname, line, err)
+		}
+		if t.Units, err = parseAmount(records[4]); err != nil {
+			return n, fmt.Errorf("%s:%d: invalid units: %w", fname, line, err)
+		}
+		if t.Amount, err = parseAmount(records[5]); err != nil {
+			return n, fmt.Errorf("%s:%d: invalid amount: %w", fname, line, err)
+		}
+		if err := insert(session, &t); err != nil {
+			return n, fmt.Errorf("%s:%d: %w", fname, line, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+var Import = subcmd.DefineCommand("import", "import transactions from CSV file (date, account, fund, type, units, amount[, note])", func(ctx context.Context, args []string) error {
+	ac, files, err := appcore.New(ctx, args)
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	if len(files) == 0 {
+		return errors.New("no files to import as transaction")
+	}
+	return xormhelper.Tx(ac.ORM, func(session *xorm.Session) error {
+		for _, f := range files {
+			n, err := importFile(session, f)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "%s: %d transactions imported\n", f, n)
+		}
+		return nil
+	})
+})
+
+var List = subcmd.DefineCommand("list", "list transactions", func(ctx context.Context, args []string) error {
+	var (
+		account  string
+		fund     string
+		from, to dataobj.Date
+		format   string
+	)
+	ac, _, err := appcore.New(ctx, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&account, "account", "", "filter by account ID")
+		fs.StringVar(&fund, "fund", "", "filter by fund ID")
+		fs.Var(&from, "from", "first date of transactions (YYYY-MM-DD)")
+		fs.Var(&to, "to", "last date of transactions (YYYY-MM-DD)")
+		fs.StringVar(&format, "format", string(output.Table), "output format: "+output.FormatNames())
+	})
+	if err != nil {
+		return err
+	}
+	defer ac.Close()
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	session := ac.ORM.NewSession()
+	defer session.Close()
+	if account != "" {
+		session.And("account_id = ?", account)
+	}
+	if fund != "" {
+		session.And("fund_id = ?", fund)
+	}
+	if !from.IsZero() {
+		session.And("date >= ?", from)
+	}
+	if !to.IsZero() {
+		session.And("date <= ?", to)
+	}
+	var list []dataobj.Transaction
+	if err := session.OrderBy("date, id").Find(&list); err != nil {
+		return err
+	}
+	w, err := output.NewWriter(os.Stdout, f)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteHeader([]string{"id", "date", "account", "fund", "type", "units", "amount", "note"}); err != nil {
+		return err
+	}
+	for _, t := range list {
+		if err := w.WriteRow([]any{t.ID, t.Date, t.AccountID, t.FundID, t.Type, t.Units, t.Amount, t.Note}); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+})
+
+var Set = subcmd.DefineSet("txn", "operate transactions",
+	Add,
+	Import,
+	List,
+)